@@ -0,0 +1,52 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"example.com/dev/k8s/manifests"
+	"example.com/dev/k8s/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var chartOptions manifests.ChartOptions
+
+// chartCmd represents the chart command
+var chartCmd = &cobra.Command{
+	Use:   "chart CHART_DIR",
+	Short: "Get k8s resources of a helm chart",
+	Long:  `Render a local helm chart without a cluster, like helm template, and get the resources of the rendered manifests, the same as the resource command`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		units, err := utils.ParseUnits(unitsFlag)
+		cobra.CheckErr(err)
+		cobra.CheckErr(validateStdoutSinks())
+		options := collectOptions()
+		options.CountDaemonSetNodes = true
+		objects, err := manifests.RenderChart(args[0], chartOptions)
+		cobra.CheckErr(err)
+		clientset, dynamicClient, err = manifests.NewClients(objects, options.CustomResources)
+		cobra.CheckErr(err)
+		reportResources(options, units)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(chartCmd)
+
+	chartCmd.Flags().StringArrayVarP(&chartOptions.ValueFiles, "values", "f", []string{}, "values file of the chart, can be repeated")
+
+	chartCmd.Flags().StringArrayVar(&chartOptions.Values, "set", []string{}, "set values of the chart, e.g. replicaCount=3, can be repeated")
+
+	chartCmd.Flags().StringVar(&chartOptions.ReleaseName, "release-name", "release", "release name the chart is rendered with")
+
+	chartCmd.Flags().StringVarP(&chartOptions.Namespace, "namespace", "n", manifests.DefaultNamespace, "release namespace the chart is rendered with")
+
+	addNodesFlags(chartCmd)
+
+	addReportFlags(chartCmd)
+
+	// not addCollectFlags, -n is the release namespace
+	addListFlags(chartCmd)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"example.com/dev/k8s/controllers"
+	"example.com/dev/k8s/utils"
+	"fmt"
+	"k8s.io/klog/v2"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const contextSourcePrefix = "context:"
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD NEW",
+	Short: "Compare two resource reports",
+	Long: `Compare two resource reports: added and removed controllers, replica changes, request/limit deltas of the
+containers and the aggregated deltas of the namespaces.
+
+OLD and NEW are json reports written by the resource command with --json, or context:NAME to collect the report from
+the kubeconfig context NAME, context: collects from the current context.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		units, err := utils.ParseUnits(unitsFlag)
+		cobra.CheckErr(err)
+		var collectErrors int
+		reports := make([]controllers.Report, 0, len(args))
+		for _, source := range args {
+			report := loadReport(source)
+			for _, collectError := range report.Errors {
+				klog.Warningf("%s: %v", source, collectError)
+			}
+			collectErrors += len(report.Errors)
+			reports = append(reports, report)
+		}
+		diff := controllers.DiffReports(reports[0], reports[1])
+		cobra.CheckErr(utils.WriteTable(os.Stdout, utils.ConvertControllerDiffToCsv(diff, units)))
+		fmt.Println()
+		cobra.CheckErr(utils.WriteTable(os.Stdout, utils.ConvertNamespaceDiffToCsv(diff, units)))
+		if len(jsonFile) > 0 {
+			cobra.CheckErr(utils.WriteJsonFile(diff, jsonFile))
+		}
+		if len(excelFile) > 0 {
+			cobra.CheckErr(utils.WriteDiffExcelFile(diff, excelFile, units))
+		}
+		if collectErrors > 0 {
+			cobra.CheckErr(fmt.Errorf("%d errors occurred while collecting resources, the diff is incomplete", collectErrors))
+		}
+	},
+}
+
+// loadReport reads the json report of source, or collects it from the kubeconfig context of source.
+func loadReport(source string) controllers.Report {
+	if !strings.HasPrefix(source, contextSourcePrefix) {
+		report, err := utils.ReadJsonReport(source)
+		cobra.CheckErr(err)
+		return report
+	}
+	kubeClient, kubeDynamicClient, err := newClients(strings.TrimPrefix(source, contextSourcePrefix))
+	cobra.CheckErr(err)
+	return collectReport(kubeClient, kubeDynamicClient, collectOptions())
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVar(&jsonFile, "json", "", "json file path for the diff")
+
+	diffCmd.Flags().StringVar(&excelFile, "excel", "", "excel file path for the diff")
+
+	addUnitsFlags(diffCmd)
+
+	addCollectFlags(diffCmd)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"example.com/dev/k8s/controllers"
+	"example.com/dev/k8s/utils"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var nodeSelector string
+
+// nodesCmd represents the nodes command
+var nodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "Get the allocation of the nodes",
+	Long: `List the nodes with their capacity and allocatable cpu, memory, ephemeral storage, pods and extended resources,
+the sums of the requests and limits of the pods scheduled on every node, and their ratios to the allocatable. A limit
+ratio above 1 is overcommitted. The requests of the pending pods without node are reported separately.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		units, err := utils.ParseUnits(unitsFlag)
+		cobra.CheckErr(err)
+		if output != "" && output != outputTable && output != outputWide && output != outputJson && output != outputYaml && output != outputCsv {
+			cobra.CheckErr(fmt.Errorf("unknown output format %q, it must be %s, %s, %s, %s or %s", output, outputTable, outputWide, outputJson, outputYaml, outputCsv))
+		}
+		cobra.CheckErr(validateStdoutSinks())
+		initClient()
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		report, err := controllers.GetNodeReport(ctx, clientset, nodeSelector, controllers.Options{PageSize: pageSize, DebugInfo: debugInfo})
+		cobra.CheckErr(err)
+		if len(jsonFile) > 0 {
+			cobra.CheckErr(utils.WriteJsonFile(report, jsonFile))
+		}
+		if len(csvFile) > 0 {
+			cobra.CheckErr(utils.WriteCsvFile(utils.ConvertNodesToCsv(report, units), nil, csvFile))
+		}
+		if len(excelFile) > 0 {
+			cobra.CheckErr(utils.WriteNodesExcelFile(report, excelFile, units))
+		}
+		format := output
+		if format == "" && len(jsonFile) == 0 && len(csvFile) == 0 && len(excelFile) == 0 {
+			format = outputTable
+		}
+		if format == outputTable || format == outputWide {
+			cobra.CheckErr(utils.WriteTable(os.Stdout, utils.ConvertNodesToTable(report, units, format == outputWide)))
+		} else if format == outputJson {
+			cobra.CheckErr(utils.WriteJsonFile(report, utils.StdoutPath))
+		} else if format == outputYaml {
+			cobra.CheckErr(utils.WriteYamlFile(report, utils.StdoutPath))
+		} else if format == outputCsv {
+			cobra.CheckErr(utils.WriteCsvFile(utils.ConvertNodesToCsv(report, units), nil, utils.StdoutPath))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nodesCmd)
+
+	nodesCmd.Flags().StringVarP(&nodeSelector, "selector", "l", "", "label selector of the nodes, e.g. node-role.kubernetes.io/worker, all nodes if not specified")
+
+	nodesCmd.Flags().StringVar(&jsonFile, "json", "", "json file path for result, - writes to stdout")
+
+	nodesCmd.Flags().StringVar(&csvFile, "csv", "", "csv file path for result, - writes to stdout")
+
+	nodesCmd.Flags().StringVar(&excelFile, "excel", "", "excel file path for result, - writes to stdout")
+
+	nodesCmd.Flags().StringVarP(&output, "output", "o", "", "print the result to stdout: table, wide, json, yaml or csv, default table when no file is written")
+
+	addUnitsFlags(nodesCmd)
+
+	nodesCmd.Flags().Int64Var(&pageSize, "page-size", 500, "number of objects of every list request, 0 lists everything at once")
+
+	nodesCmd.Flags().DurationVar(&timeout, "timeout", 0, "timeout of the whole collection, 0 means no timeout")
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"example.com/dev/k8s/controllers"
+	"example.com/dev/k8s/prometheus"
+	"example.com/dev/k8s/utils"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var prometheusURL, historyWindow string
+
+// recommendCmd represents the recommend command
+var recommendCmd = &cobra.Command{
+	Use:   "recommend",
+	Short: "Recommend container requests and limits from the usage history",
+	Long: `Recommend the requests and limits of every container from its p50, p95 and max usage over a window queried from
+Prometheus, plus the headroom of the first policy of recommendPolicies in the config file selecting its controller:
+
+prometheus:
+  url: http://prometheus.monitoring:9090
+  window: 14d
+  selector: cluster="prod"
+recommendPolicies:
+- name: batch
+  namespaces: [batch]
+  cpuRequest: {percentile: p50}
+  memoryRequest: {percentile: max, headroom: 0.1}
+  memoryLimit: {percentile: max, headroom: 0.1}
+
+The default policy requests p95 cpu plus 10% without limit, and p95 memory plus 20% with the max plus 20% as limit.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		units, err := utils.ParseUnits(unitsFlag)
+		cobra.CheckErr(err)
+		if output != "" && output != outputTable && output != outputJson && output != outputYaml && output != outputCsv {
+			cobra.CheckErr(fmt.Errorf("unknown output format %q, it must be %s, %s, %s or %s", output, outputTable, outputJson, outputYaml, outputCsv))
+		}
+		var policies []controllers.RecommendPolicy
+		cobra.CheckErr(viper.UnmarshalKey(RECOMMENDPOLICIESKEY, &policies))
+		for _, policy := range policies {
+			cobra.CheckErr(controllers.ValidateRecommendPolicy(policy))
+		}
+		options := historyOptions()
+		initClient()
+		report := collectReport(clientset, dynamicClient, collectOptions())
+		addUsageHistory(report.Responses, options)
+		recommendations := controllers.Recommend(report.Responses, policies)
+		if output == outputJson {
+			cobra.CheckErr(utils.WriteJsonFile(recommendations, utils.StdoutPath))
+		} else if output == outputYaml {
+			cobra.CheckErr(utils.WriteYamlFile(recommendations, utils.StdoutPath))
+		} else if output == outputCsv {
+			cobra.CheckErr(utils.WriteCsvFile(utils.ConvertRecommendationsToCsv(recommendations, units), nil, utils.StdoutPath))
+		} else {
+			cobra.CheckErr(utils.WriteTable(os.Stdout, utils.ConvertRecommendationsToTable(recommendations, units)))
+		}
+		if len(report.Errors) > 0 {
+			cobra.CheckErr(fmt.Errorf("%d errors occurred while collecting resources, the recommendations are incomplete", len(report.Errors)))
+		}
+	},
+}
+
+// historyOptions returns the prometheus options of the config file overridden by the flags.
+func historyOptions() prometheus.HistoryOptions {
+	var options prometheus.HistoryOptions
+	cobra.CheckErr(viper.UnmarshalKey(PROMETHEUSKEY, &options))
+	if prometheusURL != "" {
+		options.URL = prometheusURL
+	}
+	if historyWindow != "" {
+		options.Window = historyWindow
+	}
+	cobra.CheckErr(options.Validate())
+	return options
+}
+
+// addUsageHistory queries the usage history of the containers of items from Prometheus within the timeout.
+func addUsageHistory(items []controllers.ControllerItem, options prometheus.HistoryOptions) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	client := prometheus.NewClient(options.URL, options.BearerToken)
+	cobra.CheckErr(prometheus.AddUsageHistory(ctx, client, items, options))
+}
+
+// addPrometheusFlags adds the flags overriding the prometheus settings of the config file.
+func addPrometheusFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&prometheusURL, "prometheus-url", "", "url of the Prometheus API, overrides prometheus.url of the config file")
+
+	cmd.Flags().StringVar(&historyWindow, "window", "", "window of the usage history, e.g. 14d, overrides prometheus.window of the config file, default "+prometheus.DefaultWindow)
+}
+
+func init() {
+	rootCmd.AddCommand(recommendCmd)
+
+	recommendCmd.Flags().StringVarP(&output, "output", "o", "", "print the recommendations to stdout: table, json, yaml or csv, default table")
+
+	addUnitsFlags(recommendCmd)
+
+	addPrometheusFlags(recommendCmd)
+
+	addCollectFlags(recommendCmd)
+}
@@ -4,66 +4,333 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	"example.com/dev/k8s/controllers"
+	"example.com/dev/k8s/manifests"
+	"example.com/dev/k8s/prometheus"
 	"example.com/dev/k8s/utils"
+	"fmt"
 	"k8s.io/klog/v2"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 )
 
 // resourceCmd represents the resource command
 
-var requestNamespaces []string
-var jsonFile, csvFile, excelFile string
-var debugInfo bool
+var requestNamespaces, fromFiles, fromDirs, kustomizeDirs, nodesFiles, groupBy []string
+var jsonFile, csvFile, excelFile, unitsFlag, output string
+var debugInfo, keepGoing, failOnError, metrics, history bool
+var workers int
+var pageSize int64
+var timeout time.Duration
 
 var resourceCmd = &cobra.Command{
 	Use:   "resource",
 	Short: "Get k8s resources",
-	Long:  `Get k8s resources: namespace, deployment, statefulset`,
+	Long:  `Get k8s resources: namespace, deployment, statefulset, daemonset, job, cronjob and pods not owned by any of them, with the min and max replicas of their HorizontalPodAutoscalers, the footprint at the max scale and the peak footprint during a rollout by update strategy`,
 	Run: func(cmd *cobra.Command, args []string) {
-		var namespaces []string
-		var err error
-		if len(requestNamespaces) > 0 {
-			namespaces = requestNamespaces
-		} else {
-			namespaces, err = controllers.GetNamespaces(clientset)
+		units, err := utils.ParseUnits(unitsFlag)
+		cobra.CheckErr(err)
+		cobra.CheckErr(validateStdoutSinks())
+		options := collectOptions()
+		initCollectClients(options)
+		reportResources(options, units)
+	},
+}
+
+// fromManifests returns whether the resources are collected from the manifests of the manifest flags.
+func fromManifests() bool {
+	return len(fromFiles) > 0 || len(fromDirs) > 0 || len(kustomizeDirs) > 0
+}
+
+// manifestSource holds the clients of a manifest source, name tags the controllers collected from them.
+type manifestSource struct {
+	name          string
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface
+}
+
+// manifestSources are collected separately, e.g. every kustomization, when the manifest flags are set.
+var manifestSources []manifestSource
+
+// initCollectClients creates the clients of every source of the manifest flags, or of the cluster without them.
+// clientset and dynamicClient are the clients of the first source, e.g. to list its nodes.
+func initCollectClients(options controllers.Options) {
+	if fromManifests() {
+		if metrics {
+			cobra.CheckErr(fmt.Errorf("--metrics needs a cluster, it can not be used with manifests"))
+		}
+		sources, err := manifests.LoadSources(fromFiles, fromDirs, kustomizeDirs)
+		cobra.CheckErr(err)
+		for _, source := range sources {
+			sourceClientset, sourceDynamicClient, err := manifests.NewClients(source.Objects, options.CustomResources)
 			cobra.CheckErr(err)
+			manifestSources = append(manifestSources, manifestSource{name: source.Name, clientset: sourceClientset, dynamicClient: sourceDynamicClient})
 		}
-		klog.Infof("requests namespace %#v", namespaces)
-		result, err := controllers.GetControllerItems(clientset, namespaces, debugInfo)
+		clientset, dynamicClient = manifestSources[0].clientset, manifestSources[0].dynamicClient
+	} else {
+		initClient()
+	}
+}
+
+// collectOptions returns the options of the resource flags and the config file.
+func collectOptions() controllers.Options {
+	klog.Infof("requests namespace %#v", requestNamespaces)
+	options := controllers.Options{
+		Namespaces:  requestNamespaces,
+		FailOnError: failOnError || !keepGoing,
+		Workers:     workers,
+		PageSize:    pageSize,
+		Metrics:     metrics,
+		DebugInfo:   debugInfo,
+		// manifests have no DaemonSet status
+		CountDaemonSetNodes: fromManifests() || len(nodesFiles) > 0,
+	}
+	if len(nodesFiles) > 0 {
+		nodes, err := manifests.LoadNodes(nodesFiles)
 		cobra.CheckErr(err)
-		if len(jsonFile) > 0 {
-			cobra.CheckErr(
-				utils.WriteJsonFile(
-					struct {
-						Responses []controllers.ControllerItem `json:"responses,omitempty"`
-					}{
-						result,
-					},
-					jsonFile))
+		options.Nodes = nodes
+	}
+	for _, group := range groupBy {
+		cobra.CheckErr(controllers.ValidateGroupBy(group))
+		if strings.HasPrefix(group, controllers.GroupByLabelPrefix) {
+			options.NamespaceLabels = true
+		}
+	}
+	cobra.CheckErr(viper.UnmarshalKey(CUSTOMRESOURCESKEY, &options.CustomResources))
+	cobra.CheckErr(viper.UnmarshalKey(CSISIZEATTRIBUTESKEY, &options.CSISizeAttributes))
+	return options
+}
+
+// collectReport collects the resources with kubeClient and kubeDynamicClient within the timeout.
+func collectReport(kubeClient kubernetes.Interface, kubeDynamicClient dynamic.Interface, options controllers.Options) controllers.Report {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result, collectErrors, err := controllers.GetControllerItems(ctx, kubeClient, kubeDynamicClient, options)
+	cobra.CheckErr(err)
+	return controllers.Report{Responses: result, Errors: collectErrors}
+}
+
+// collectSources collects the report of every manifest source, the controllers of a kustomization are tagged by its
+// directory, or the report of clientset and dynamicClient without manifest sources.
+func collectSources(options controllers.Options) controllers.Report {
+	if len(manifestSources) == 0 {
+		return collectReport(clientset, dynamicClient, options)
+	}
+	var report controllers.Report
+	for _, source := range manifestSources {
+		sourceReport := collectReport(source.clientset, source.dynamicClient, options)
+		for index := range sourceReport.Responses {
+			sourceReport.Responses[index].Source = source.name
 		}
-		if len(csvFile) > 0 {
-			cobra.CheckErr(utils.WriteCsvFile(controllers.ConvertResultToCsv(result), nil, csvFile))
+		report.Responses = append(report.Responses, sourceReport.Responses...)
+		report.Errors = append(report.Errors, sourceReport.Errors...)
+	}
+	return report
+}
+
+const (
+	outputTable = "table"
+	outputWide  = "wide"
+	outputJson  = "json"
+	outputYaml  = "yaml"
+	outputCsv   = "csv"
+)
+
+// reportCsv returns the csv records of report: the result, the summary and the errors separated by empty records.
+func reportCsv(report controllers.Report, units utils.Units) [][]string {
+	content := utils.ConvertResultToCsv(report.Responses, units)
+	if len(report.Summary) > 0 {
+		content = append(append(content, []string{}), utils.ConvertSummaryToCsv(report.Summary, units)...)
+	}
+	if len(report.Errors) > 0 {
+		content = append(append(content, []string{}), utils.ConvertErrorsToCsv(report.Errors)...)
+	}
+	return content
+}
+
+// printReport prints report to stdout in the format of --output, the table is printed when no file is written.
+func printReport(report controllers.Report, units utils.Units, templatePrinter *utils.TemplatePrinter) error {
+	if templatePrinter != nil {
+		return templatePrinter.Print(os.Stdout, report)
+	}
+	format := output
+	if format == "" && len(jsonFile) == 0 && len(csvFile) == 0 && len(excelFile) == 0 {
+		format = outputTable
+	}
+	if format == outputTable || format == outputWide {
+		if err := utils.WriteTable(os.Stdout, utils.ConvertResultToTable(report.Responses, units, format == outputWide)); err != nil {
+			return err
 		}
-		if len(excelFile) > 0 {
-			cobra.CheckErr(utils.WriteExcelFile(result, excelFile, "resources"))
+		if len(report.Summary) > 0 {
+			fmt.Println()
+			if err := utils.WriteTable(os.Stdout, utils.ConvertSummaryToCsv(report.Summary, units)); err != nil {
+				return err
+			}
 		}
-	},
+		if len(report.Errors) > 0 {
+			fmt.Fprintln(os.Stderr)
+			return utils.WriteTable(os.Stderr, utils.ConvertErrorsToCsv(report.Errors))
+		}
+		return nil
+	} else if format == outputJson {
+		return utils.WriteJsonFile(report, utils.StdoutPath)
+	} else if format == outputYaml {
+		return utils.WriteYamlFile(report, utils.StdoutPath)
+	} else if format == outputCsv {
+		return utils.WriteCsvFile(reportCsv(report, units), nil, utils.StdoutPath)
+	}
+	return nil
+}
+
+func validateOutput() error {
+	if output == "" || output == outputTable || output == outputWide || output == outputJson || output == outputYaml || output == outputCsv {
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, it must be %s, %s, %s, %s, %s, %sTEMPLATE, %sTEMPLATE or %sSPEC", output,
+		outputTable, outputWide, outputJson, outputYaml, outputCsv, utils.GoTemplatePrefix, utils.JsonPathPrefix, utils.CustomColumnsPrefix)
+}
+
+// validateStdoutSinks returns an error when more than one of --json, --csv, --excel and --output writes to stdout, their
+// documents would be interleaved.
+func validateStdoutSinks() error {
+	var sinks []string
+	for _, file := range []struct{ flag, path string }{{"--json", jsonFile}, {"--csv", csvFile}, {"--excel", excelFile}} {
+		if file.path == utils.StdoutPath {
+			sinks = append(sinks, file.flag+" "+utils.StdoutPath)
+		}
+	}
+	if output != "" {
+		sinks = append(sinks, "--output "+output)
+	}
+	if len(sinks) > 1 {
+		return fmt.Errorf("only one of %s can write to stdout", strings.Join(sinks, ", "))
+	}
+	return nil
+}
+
+// reportResources collects the resources with clientset and dynamicClient, writes the json, csv and excel files and
+// prints the report.
+func reportResources(options controllers.Options, units utils.Units) {
+	templatePrinter, err := utils.NewTemplatePrinter(output, units)
+	cobra.CheckErr(err)
+	if templatePrinter == nil {
+		cobra.CheckErr(validateOutput())
+	}
+	var prometheusOptions prometheus.HistoryOptions
+	if history {
+		prometheusOptions = historyOptions()
+	}
+	report := collectSources(options)
+	if history {
+		addUsageHistory(report.Responses, prometheusOptions)
+	}
+	for _, group := range groupBy {
+		report.Summary = append(report.Summary, controllers.AggregateControllerItems(report.Responses, group)...)
+	}
+	if len(jsonFile) > 0 {
+		cobra.CheckErr(utils.WriteJsonFile(report, jsonFile))
+	}
+	if len(csvFile) > 0 {
+		cobra.CheckErr(utils.WriteCsvFile(reportCsv(report, units), nil, csvFile))
+	}
+	if len(excelFile) > 0 {
+		cobra.CheckErr(utils.WriteExcelFile(report, excelFile, "resources", units))
+	}
+	cobra.CheckErr(printReport(report, units, templatePrinter))
+	if len(report.Errors) > 0 {
+		cobra.CheckErr(fmt.Errorf("%d errors occurred while collecting resources, the result is incomplete", len(report.Errors)))
+	}
+}
+
+// addReportFlags adds the flags of the result files shared by the commands which write a resource report.
+func addReportFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&jsonFile, "json", "", "json file path for result, - writes to stdout")
+
+	cmd.Flags().StringVar(&csvFile, "csv", "", "csv file path for result, - writes to stdout")
+
+	cmd.Flags().StringVar(&excelFile, "excel", "", "excel file path for result, - writes to stdout")
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "print the result to stdout: table, wide, json, yaml, csv, go-template=TEMPLATE, jsonpath=TEMPLATE or custom-columns=HEADER:JSONPATH,..., "+
+		"the templates use the json field names, go templates format quantities in --units by {{cpu .total.requestCpu}} and {{memory .total.requestMem}}, "+
+		"table is printed if no result file is given")
+
+	cmd.Flags().StringArrayVar(&groupBy, "group-by", []string{}, "sum the controllers by namespace, type, source or label:<key> of the pod template or the namespace, e.g. label:team, can be repeated")
+
+	addUnitsFlags(cmd)
+}
+
+// addUnitsFlags adds the flags of the units the quantities are printed in and of the debug info, shared by every command.
+func addUnitsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&unitsFlag, "units", "Mi,millicores", "units of the table, csv, excel and go template output, json and yaml keep the exact quantities: "+
+		"memory unit (bytes, Ki, Mi, Gi, Ti, KB, MB, GB, TB) and cpu unit (cores, millicores)")
+
+	cmd.Flags().BoolVar(&debugInfo, "debug", false, "show debug info")
+}
+
+// addManifestFlags adds the flags of the manifests collected instead of a cluster.
+func addManifestFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&fromFiles, "from-file", "f", []string{}, "compute the resources of the YAML/JSON manifests in the file instead of a cluster, no kubeconfig is needed")
+
+	cmd.Flags().StringArrayVar(&fromDirs, "from-dir", []string{}, "compute the resources of the .yaml, .yml and .json manifests found recursively in the directory instead of a cluster")
+
+	cmd.Flags().StringArrayVarP(&kustomizeDirs, "kustomize", "k", []string{}, "compute the resources of the kustomization directory, e.g. an overlay, built like kustomize build instead of a cluster, "+
+		"every kustomization is collected separately and its controllers are tagged by its directory in the source column, e.g. to compare overlays")
+}
+
+// addNodesFlags adds the flags of the nodes the DaemonSets are counted on.
+func addNodesFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&nodesFiles, "nodes", []string{}, "count the replicas of the DaemonSets on the nodes of the YAML/JSON manifests in the file, e.g. saved by kubectl get nodes -o yaml, "+
+		"instead of their status or the Node manifests, a DaemonSet has 1 replica without nodes, can be repeated")
+}
+
+// addListFlags adds the flags of the list requests and of their errors.
+func addListFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&workers, "workers", 10, "number of concurrent list requests")
+
+	cmd.Flags().Int64Var(&pageSize, "page-size", 500, "number of objects of every list request, 0 lists everything at once")
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "timeout of the whole collection, 0 means no timeout")
+
+	cmd.Flags().BoolVar(&keepGoing, "keep-going", true, "skip the namespaces and kinds which can not be collected, report them and exit with non-zero code")
+
+	cmd.Flags().BoolVar(&failOnError, "fail-on-error", false, "abort at the first namespace or kind which can not be collected, same as --keep-going=false")
+	cmd.MarkFlagsMutuallyExclusive("keep-going", "fail-on-error")
+}
+
+// addCollectFlags adds the flags of the collection from a cluster.
+func addCollectFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&requestNamespaces, "namespace", "n", []string{}, "specified namespace, all namespaces are listed cluster wide if not specified")
+
+	addListFlags(cmd)
+
+	cmd.Flags().BoolVar(&metrics, "metrics", false, "add the current cpu/memory usage of the pods from the metrics API (metrics-server), and its ratios to the requests and limits")
 }
 
 func init() {
 	rootCmd.AddCommand(resourceCmd)
 
-	resourceCmd.Flags().StringArrayVarP(&requestNamespaces, "namespace", "n", []string{}, "specified namespace")
+	addManifestFlags(resourceCmd)
+
+	addNodesFlags(resourceCmd)
 
-	resourceCmd.Flags().StringVar(&jsonFile, "json", "", "json file path for result")
+	resourceCmd.Flags().BoolVar(&history, "history", false, "add the p50, p95 and max usage of the containers over a window from Prometheus, see the prometheus key of the config file")
 
-	resourceCmd.Flags().StringVar(&csvFile, "csv", "", "csv file path for result")
+	addPrometheusFlags(resourceCmd)
 
-	resourceCmd.Flags().StringVar(&excelFile, "excel", "", "excel file path for result")
+	addReportFlags(resourceCmd)
 
-	resourceCmd.Flags().BoolVar(&debugInfo, "debug", false, "show debug info")
+	addCollectFlags(resourceCmd)
 
 	// Here you will define your flags and configuration settings.
 
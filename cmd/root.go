@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
@@ -17,12 +18,20 @@ import (
 )
 
 const (
-	KUBECONFIGKEY = "kubeconfig"
+	KUBECONFIGKEY        = "kubeconfig"
+	CUSTOMRESOURCESKEY   = "customResources"
+	CSISIZEATTRIBUTESKEY = "csiSizeAttributes"
+	PROMETHEUSKEY        = "prometheus"
+	RECOMMENDPOLICIESKEY = "recommendPolicies"
+	NODEPOOLSKEY         = "nodePools"
 )
 
 var cfgFile string
+var qps float32
+var burst int
 
-var clientset *kubernetes.Clientset
+var clientset kubernetes.Interface
+var dynamicClient dynamic.Interface
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -46,7 +55,7 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig, initClient, initLoggingFlags)
+	cobra.OnInitialize(initConfig, initLoggingFlags)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
@@ -58,18 +67,39 @@ func init() {
 		rootCmd.PersistentFlags().String(KUBECONFIGKEY, "", "absolute path to the kubeconfig file")
 	}
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.k8s.yaml)")
+	rootCmd.PersistentFlags().Float32Var(&qps, "qps", 50, "maximum queries per second to the apiserver")
+	rootCmd.PersistentFlags().IntVar(&burst, "burst", 100, "maximum burst of queries to the apiserver")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 }
 
-func initClient() {
-	if config, err := clientcmd.BuildConfigFromFlags("", viper.GetString(KUBECONFIGKEY)); err != nil {
-		cobra.CheckErr(err)
-	} else {
-		clientset, err = kubernetes.NewForConfig(config)
-		cobra.CheckErr(err)
+// newClients builds the clients of the kubeconfig context, the current context is used if context is empty.
+func newClients(context string) (kubernetes.Interface, dynamic.Interface, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: viper.GetString(KUBECONFIGKEY)},
+		&clientcmd.ConfigOverrides{CurrentContext: context}).ClientConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	config.QPS = qps
+	config.Burst = burst
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
 	}
+	kubeDynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kubeClient, kubeDynamicClient, nil
+}
+
+// initClient builds the clients of the cluster, it is only called by the commands which talk to a cluster.
+func initClient() {
+	var err error
+	clientset, dynamicClient, err = newClients("")
+	cobra.CheckErr(err)
 }
 
 func initLoggingFlags() {
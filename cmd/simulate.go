@@ -0,0 +1,111 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"example.com/dev/k8s/controllers"
+	"example.com/dev/k8s/utils"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var poolLabel string
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Simulate how many nodes of every pool the controllers need",
+	Long: `Pack the effective pod requests of every replica of the controllers on the nodes of every pool separately, first fit
+decreasing, and report the number of nodes needed, the capacity stranded on them and the pods which can not be placed.
+The DaemonSets are not packed but run on every node. The pools are the nodePools of the config file:
+
+nodePools:
+- name: m5.xlarge
+  cpu: 3920m
+  memory: 14Gi
+  pods: 58
+- name: g4dn.xlarge
+  cpu: 3920m
+  memory: 15Gi
+  maxNodes: 10
+  labels: {accelerator: nvidia}
+  taints: [{key: nvidia.com/gpu, effect: NoSchedule}]
+
+or the nodes of the cluster grouped by --pool-label, the shape of a pool is the smallest allocatable of its nodes.
+A DaemonSet runs on the nodes of a pool when its node selector, required node affinity and tolerations match the
+labels and taints of the pool, which are the ones common to its nodes for --pool-label.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		units, err := utils.ParseUnits(unitsFlag)
+		cobra.CheckErr(err)
+		if output != "" && output != outputTable && output != outputJson && output != outputYaml && output != outputCsv {
+			cobra.CheckErr(fmt.Errorf("unknown output format %q, it must be %s, %s, %s or %s", output, outputTable, outputJson, outputYaml, outputCsv))
+		}
+		var pools []controllers.NodePool
+		if poolLabel == "" {
+			cobra.CheckErr(viper.UnmarshalKey(NODEPOOLSKEY, &pools))
+			if len(pools) == 0 {
+				cobra.CheckErr(fmt.Errorf("no node pool, set %s in the config file or --pool-label", NODEPOOLSKEY))
+			}
+			for _, pool := range pools {
+				cobra.CheckErr(controllers.ValidateNodePool(pool))
+			}
+		}
+		options := collectOptions()
+		initCollectClients(options)
+		if poolLabel != "" {
+			ctx := context.Background()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			pools, err = controllers.GetNodePools(ctx, clientset, poolLabel, options)
+			cobra.CheckErr(err)
+			if len(pools) == 0 {
+				cobra.CheckErr(fmt.Errorf("no node found to group by %s", poolLabel))
+			}
+		}
+		report := collectSources(options)
+		simulations, err := controllers.Simulate(report.Responses, pools)
+		cobra.CheckErr(err)
+		if output == outputJson {
+			cobra.CheckErr(utils.WriteJsonFile(simulations, utils.StdoutPath))
+		} else if output == outputYaml {
+			cobra.CheckErr(utils.WriteYamlFile(simulations, utils.StdoutPath))
+		} else if output == outputCsv {
+			content := utils.ConvertSimulationToCsv(simulations, units)
+			if unschedulable := utils.ConvertUnschedulableToCsv(simulations); len(unschedulable) > 1 {
+				content = append(append(content, []string{}), unschedulable...)
+			}
+			cobra.CheckErr(utils.WriteCsvFile(content, nil, utils.StdoutPath))
+		} else {
+			cobra.CheckErr(utils.WriteTable(os.Stdout, utils.ConvertSimulationToTable(simulations, units)))
+			if unschedulable := utils.ConvertUnschedulableToCsv(simulations); len(unschedulable) > 1 {
+				fmt.Println()
+				cobra.CheckErr(utils.WriteTable(os.Stdout, unschedulable))
+			}
+		}
+		if len(report.Errors) > 0 {
+			cobra.CheckErr(fmt.Errorf("%d errors occurred while collecting resources, the simulation is incomplete", len(report.Errors)))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+
+	simulateCmd.Flags().StringVar(&poolLabel, "pool-label", "", "simulate the node pools of the cluster grouped by the value of this node label, e.g. node.kubernetes.io/instance-type, instead of nodePools of the config file")
+
+	simulateCmd.Flags().StringVarP(&output, "output", "o", "", "print the simulation to stdout: table, json, yaml or csv, default table")
+
+	addUnitsFlags(simulateCmd)
+
+	addManifestFlags(simulateCmd)
+
+	addCollectFlags(simulateCmd)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// AutoscalerItem is the replica range of the HorizontalPodAutoscaler scaling a controller, MaxTotal is the
+// EffectivePod of the controller multiplied by MaxReplicas, its footprint at the max scale.
+type AutoscalerItem struct {
+	Name        string       `json:"name"`
+	MinReplicas int32        `json:"minReplicas"`
+	MaxReplicas int32        `json:"maxReplicas"`
+	MaxTotal    ResourceItem `json:"maxTotal"`
+}
+
+// MaxReplicas returns the max replicas of the autoscaler of item, its replicas without autoscaler.
+func (item ControllerItem) MaxReplicas() int32 {
+	if item.Autoscaler == nil {
+		return item.Replicas
+	}
+	return item.Autoscaler.MaxReplicas
+}
+
+// MaxTotal returns the footprint of item at the max scale of its autoscaler, its Total without autoscaler.
+func (item ControllerItem) MaxTotal() ResourceItem {
+	if item.Autoscaler == nil {
+		return item.Total
+	}
+	return item.Autoscaler.MaxTotal
+}
+
+func multiplyResourceItem(resourceItem ResourceItem, replicas int32) ResourceItem {
+	return ResourceItem{
+		RequestCPU:              multiplyQuantity(resourceItem.RequestCPU, replicas),
+		RequestMem:              multiplyQuantity(resourceItem.RequestMem, replicas),
+		RequestEphemeralStorate: multiplyQuantity(resourceItem.RequestEphemeralStorate, replicas),
+		LimitCPU:                multiplyQuantity(resourceItem.LimitCPU, replicas),
+		LimitMem:                multiplyQuantity(resourceItem.LimitMem, replicas),
+		LimitEphemeralStorate:   multiplyQuantity(resourceItem.LimitEphemeralStorate, replicas),
+	}
+}
+
+// getAutoscalers returns the autoscalers of namespace by the controllerKey of their scale target.
+func getAutoscalers(ctx context.Context, clientset kubernetes.Interface, namespace string, options Options) (map[string]AutoscalerItem, error) {
+	autoscalers := map[string]AutoscalerItem{}
+	err := listPages(ctx, "horizontalpodautoscaler", namespace, options, clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List, func(autoscalerList *autoscalingv2.HorizontalPodAutoscalerList) error {
+		for _, autoscaler := range autoscalerList.Items {
+			target := autoscaler.Spec.ScaleTargetRef
+			autoscalers[controllerKey(target.Kind, autoscaler.Namespace, target.Name)] = AutoscalerItem{
+				Name: autoscaler.Name,
+				// minReplicas defaults to 1
+				MinReplicas: specReplicas(autoscaler.Spec.MinReplicas),
+				MaxReplicas: autoscaler.Spec.MaxReplicas,
+			}
+		}
+		return nil
+	})
+	return autoscalers, err
+}
+
+// addAutoscalers sets the autoscaler of the controllers of result scaled by a HorizontalPodAutoscaler. A namespace whose
+// autoscalers can not be listed for lack of permission is skipped with a warning.
+func addAutoscalers(ctx context.Context, clientset kubernetes.Interface, namespaces []string, result []ControllerItem, options Options, addErrors func(...CollectError) error) error {
+	autoscalers := make([]map[string]AutoscalerItem, len(namespaces))
+	tasks := make([]collectTask, 0, len(namespaces))
+	for index, namespace := range namespaces {
+		tasks = append(tasks, collectTask{namespace: namespace, kind: "HorizontalPodAutoscaler", collect: func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			namespaceAutoscalers, err := getAutoscalers(ctx, clientset, namespace, options)
+			if apierrors.IsForbidden(err) {
+				// the autoscalers are optional, the controllers are reported without them
+				klog.Warningf("list the autoscalers of namespace %q is forbidden, the controllers are reported without autoscaler: %v", namespace, err)
+				return nil, nil
+			}
+			autoscalers[index] = namespaceAutoscalers
+			return nil, err
+		}})
+	}
+	if err := runTasks(ctx, tasks, options.Workers, options.FailOnError); err != nil {
+		return err
+	}
+	for index, task := range tasks {
+		if task.err != nil {
+			if err := addErrors(newCollectError(task.namespace, task.kind, task.err)); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := range result {
+			item := &result[i]
+			if autoscaler, ok := autoscalers[index][controllerKey(item.ControllerType, item.Namespace, item.Controller)]; ok {
+				autoscaler.MaxTotal = multiplyResourceItem(item.EffectivePod, autoscaler.MaxReplicas)
+				item.Autoscaler = &autoscaler
+			}
+		}
+	}
+	return nil
+}
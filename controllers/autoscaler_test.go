@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testAutoscaler(name, kind, target string, minReplicas *int32, maxReplicas int32) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: objectMeta(name, nil),
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: kind, Name: target},
+			MinReplicas:    minReplicas,
+			MaxReplicas:    maxReplicas,
+		},
+	}
+}
+
+func TestGetControllerItemsAutoscalers(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(2), Template: testTemplate("100m", "128Mi")}},
+		&appsv1.StatefulSet{ObjectMeta: objectMeta("db", nil), Spec: appsv1.StatefulSetSpec{Replicas: int32Ptr(3), Template: testTemplate("1", "1Gi")}},
+		&appsv1.Deployment{ObjectMeta: objectMeta("fixed", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(1), Template: testTemplate("500m", "256Mi")}},
+		testAutoscaler("web", "Deployment", "web", int32Ptr(2), 10),
+		// minReplicas defaults to 1, the kind of the target is matched case insensitively
+		testAutoscaler("db", "StatefulSet", "db", nil, 5),
+		// the target of another kind is not matched
+		testAutoscaler("fixed", "StatefulSet", "fixed", nil, 4),
+	)
+	items, collectErrors, err := GetControllerItems(context.Background(), clientset, nil, Options{})
+	if err != nil || len(collectErrors) > 0 {
+		t.Fatalf("GetControllerItems() = %v, %v", collectErrors, err)
+	}
+
+	web := findControllerItem(items, "Deployment", "web")
+	if web == nil || web.Autoscaler == nil || web.Autoscaler.Name != "web" || web.Autoscaler.MinReplicas != 2 || web.MaxReplicas() != 10 {
+		t.Fatalf("web = %+v, want the autoscaler web from 2 to 10 replicas", web)
+	}
+	if maxTotal := web.MaxTotal(); maxTotal.RequestCPU.Cmp(resource.MustParse("1")) != 0 || maxTotal.LimitMem.Cmp(resource.MustParse("1280Mi")) != 0 {
+		t.Errorf("web max total = %+v, want 10 * 100m and 10 * 128Mi", maxTotal)
+	}
+	db := findControllerItem(items, "Statefulset", "db")
+	if db == nil || db.Autoscaler == nil || db.Autoscaler.MinReplicas != 1 || db.Replicas != 3 {
+		t.Fatalf("db = %+v, want 3 replicas and an autoscaler from 1", db)
+	}
+	fixed := findControllerItem(items, "Deployment", "fixed")
+	if fixed == nil || fixed.Autoscaler != nil || fixed.MaxReplicas() != 1 {
+		t.Fatalf("fixed = %+v, want its current replicas without autoscaler", fixed)
+	}
+	if maxTotal := fixed.MaxTotal(); maxTotal.RequestCPU.Cmp(resource.MustParse("500m")) != 0 {
+		t.Errorf("fixed max total = %+v, want its total", maxTotal)
+	}
+
+	groups := AggregateControllerItems(items, GroupByNamespace)
+	if len(groups) != 1 {
+		t.Fatalf("AggregateControllerItems() = %+v", groups)
+	}
+	// 10 web, 5 db and 1 fixed replicas
+	group := groups[0]
+	if group.Autoscalers != 2 || group.Replicas != 6 || group.MaxReplicas != 16 || group.MaxTotal.RequestCPU.Cmp(resource.MustParse("6500m")) != 0 {
+		t.Errorf("namespace group = %+v, want 2 autoscalers, 16 max replicas and 6500m max cpu", group)
+	}
+}
+
+func TestGetControllerItemsAutoscalersForbidden(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(2), Template: testTemplate("100m", "128Mi")}},
+		testAutoscaler("web", "Deployment", "web", int32Ptr(2), 10),
+	)
+	clientset.PrependReactor("list", "horizontalpodautoscalers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "autoscaling", Resource: "horizontalpodautoscalers"}, "", errors.New("rbac"))
+	})
+	// the forbidden autoscalers do not abort the collection
+	items, collectErrors, err := GetControllerItems(context.Background(), clientset, nil, Options{FailOnError: true})
+	if err != nil || len(collectErrors) > 0 {
+		t.Fatalf("GetControllerItems() = %v, %v", collectErrors, err)
+	}
+	if web := findControllerItem(items, "Deployment", "web"); web == nil || web.Autoscaler != nil || web.MaxReplicas() != 2 {
+		t.Errorf("web = %+v, want its current replicas without autoscaler", web)
+	}
+}
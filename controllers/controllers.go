@@ -3,114 +3,170 @@ package controllers
 import (
 	"context"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"strconv"
+	"k8s.io/klog/v2"
+	"sort"
+	"sync"
 )
 
+// The ControllerType of the controllers collected from the built-in kinds, the custom resources use their kind.
 const (
-	mi = 1024 * 1024
+	TypeDeployment  = "Deployment"
+	TypeStatefulSet = "Statefulset"
+	TypeDaemonSet   = "Daemonset"
+	TypeJob         = "Job"
+	TypeCronJob     = "CronJob"
+	TypeReplicaSet  = "ReplicaSet"
+	TypePod         = "Pod"
 )
 
+// ResourceItem holds the exact quantities, units are only applied when the result is rendered.
+type ResourceItem struct {
+	RequestCPU              resource.Quantity `json:"requestCpu"`
+	RequestMem              resource.Quantity `json:"requestMem"`
+	RequestEphemeralStorate resource.Quantity `json:"requestEphemeralStorate"`
+	LimitCPU                resource.Quantity `json:"limitCpu"`
+	LimitMem                resource.Quantity `json:"limitMem"`
+	LimitEphemeralStorate   resource.Quantity `json:"limitEphemeralStorate"`
+}
+
 type ContainerItem struct {
-	Name                    string `json:"name,omitempty"`
-	RequestCPU              int64  `json:"requestCpu"`
-	RequestMem              int64  `json:"requestMem"`
-	RequestEphemeralStorate int64  `json:"requestEphemeralStorate,omitempty"`
-	LimitCPU                int64  `json:"limitCpu"`
-	LimitMem                int64  `json:"limitMem"`
-	LimitEphemeralStorate   int64  `json:"limitEphemeralStorate,omitempty"`
+	Name string `json:"name,omitempty"`
+	ResourceItem
+	// Usage is the average usage of a pod when Options.Metrics is set
+	Usage *UsageItem `json:"usage,omitempty"`
+	// History is the usage over a window queried from Prometheus
+	History *UsageHistoryItem `json:"history,omitempty"`
 }
 
 type ControllerItem struct {
-	Namespace      string          `json:"namespace,omitempty"`
-	ControllerType string          `json:"controllerType,omitempty"`
-	Controller     string          `json:"controller,omitempty"`
-	Replicas       int32           `json:"replicas,omitempty"`
-	InitContainer  []ContainerItem `json:"initContainer,omitempty"`
-	Container      []ContainerItem `json:"container,omitempty"`
-	EmptyDir       int64           `json:"emptyDir,omitempty"`
-	Storage        int             `json:"storage,omitempty"`
-	StorageNoSize  bool            `json:"storageNoSize,omitempty"`
+	Namespace      string            `json:"namespace,omitempty"`
+	ControllerType string            `json:"controllerType,omitempty"`
+	Controller     string            `json:"controller,omitempty"`
+	Replicas       int32             `json:"replicas,omitempty"`
+	InitContainer  []ContainerItem   `json:"initContainer,omitempty"`
+	Container      []ContainerItem   `json:"container,omitempty"`
+	EmptyDir       resource.Quantity `json:"emptyDir"`
+	Storage        resource.Quantity `json:"storage"`
+	StorageNoSize  bool              `json:"storageNoSize,omitempty"`
+	// Labels are the pod template labels, completed by the namespace labels when Options.NamespaceLabels is set
+	Labels map[string]string `json:"labels,omitempty"`
+	// UnknownVolumes are the inline CSI volumes, formatted as name(driver), whose size is unknown
+	UnknownVolumes []string `json:"unknownVolumes,omitempty"`
+	// PersistentStorage is the storage of persistentVolumeClaim, generic ephemeral volumes and volumeClaimTemplates
+	PersistentStorage []StorageClassItem `json:"persistentStorage,omitempty"`
+	// EffectivePod is the pod level request/limit used by the scheduler, Total is EffectivePod multiplied by Replicas.
+	EffectivePod ResourceItem `json:"effectivePod"`
+	Total        ResourceItem `json:"total"`
+	// Usage sums the usage of the pods with metrics when Options.Metrics is set
+	Usage *UsageItem `json:"usage,omitempty"`
+	// Autoscaler is the HorizontalPodAutoscaler scaling the controller, Replicas are its current replicas
+	Autoscaler *AutoscalerItem `json:"autoscaler,omitempty"`
+	// Rollout is the update strategy of a Deployment, StatefulSet or DaemonSet and its peak during a rollout
+	Rollout *RolloutItem `json:"rollout,omitempty"`
+	// Placement selects the nodes a DaemonSet runs on, it is only set for DaemonSets
+	Placement *PlacementItem `json:"placement,omitempty"`
+	// Source is the kustomization directory the controller was built from, the kustomizations are collected separately
+	Source string `json:"source,omitempty"`
 }
 
-func ConvertResultToCsv(content []ControllerItem) [][]string {
-	result := [][]string{[]string{
-		"namespace", "controllerType", "controller", "replicas", "emptyDir(m)", "storage(m)", "storageNoSize",
-		"containerType", "containerName", "requestCpu", "requestMem(m)", "requestEphemeralStorage(m)", "limitCpu", "limitMem(m)", "limitEphemeralStorage(m)"}}
-	for _, controller := range content {
-		namespace := controller.Namespace
-		controllerType := controller.ControllerType
-		controllerName := controller.Controller
-		replicas := controller.Replicas
-		emptyDir := controller.EmptyDir
-		storage := controller.Storage
-		storageNoSize := controller.StorageNoSize
-		containerType := "initContainer"
-		for _, container := range controller.InitContainer {
-			result = append(result,
-				[]string{
-					namespace, controllerType, controllerName, strconv.Itoa(int(replicas)), strconv.FormatInt(emptyDir, 10), strconv.Itoa(storage), strconv.FormatBool(storageNoSize),
-					containerType, container.Name, strconv.FormatInt(container.RequestCPU, 10), strconv.FormatInt(container.RequestMem, 10), strconv.FormatInt(container.RequestEphemeralStorate, 10),
-					strconv.FormatInt(container.LimitCPU, 10), strconv.FormatInt(container.LimitMem, 10), strconv.FormatInt(container.LimitEphemeralStorate, 10),
-				})
-		}
-		containerType = "container"
-		for _, container := range controller.Container {
-			result = append(result,
-				[]string{
-					namespace, controllerType, controllerName, strconv.Itoa(int(replicas)), strconv.FormatInt(emptyDir, 10), strconv.Itoa(storage), strconv.FormatBool(storageNoSize),
-					containerType, container.Name, strconv.FormatInt(container.RequestCPU, 10), strconv.FormatInt(container.RequestMem, 10), strconv.FormatInt(container.RequestEphemeralStorate, 10),
-					strconv.FormatInt(container.LimitCPU, 10), strconv.FormatInt(container.LimitMem, 10), strconv.FormatInt(container.LimitEphemeralStorate, 10),
-				})
-		}
-	}
-	return result
-
+type volumeResult struct {
+	emptyDir      resource.Quantity
+	storage       resource.Quantity
+	storageNoSize bool
+	memStorage    bool
+	// unknownVolumes holds the inline CSI volumes whose size can not be found in their volumeAttributes
+	unknownVolumes []string
 }
 
-func generateVolumeResult(volumes []v1.Volume) (int64, int, bool, bool) {
-	var emptyDir int64 = 0
-	storage, storageNoSize, memStorage := 0, false, false
+// generateVolumeResult sums the emptyDir size limits and the inline CSI volume sizes.
+func generateVolumeResult(volumes []v1.Volume, csiSizeAttributes map[string][]string) volumeResult {
+	var result volumeResult
 	for _, volume := range volumes {
 		if volume.EmptyDir != nil {
 			if volume.EmptyDir.Medium != "" {
-				memStorage = true
+				result.memStorage = true
 			} else if volume.EmptyDir.SizeLimit == nil || volume.EmptyDir.SizeLimit.Value() == 0 {
-				storageNoSize = true
+				result.storageNoSize = true
 			} else {
-				emptyDir += volume.EmptyDir.SizeLimit.Value()
+				result.emptyDir.Add(*volume.EmptyDir.SizeLimit)
 			}
 		}
 		if volume.CSI != nil {
-			if volume.CSI.Size() == 0 {
-				storageNoSize = true
+			if size, hasStorage, err := csiVolumeSize(*volume.CSI, csiSizeAttributes); err != nil {
+				klog.Warningf("invalid size of csi volume %q: %v", volume.Name, err)
+				result.storageNoSize = true
+				result.unknownVolumes = append(result.unknownVolumes, volume.Name+"("+volume.CSI.Driver+")")
+			} else if !hasStorage {
+				continue
+			} else if size == nil {
+				result.storageNoSize = true
+				result.unknownVolumes = append(result.unknownVolumes, volume.Name+"("+volume.CSI.Driver+")")
 			} else {
-				storage += volume.CSI.Size()
+				result.storage.Add(*size)
 			}
 		}
 	}
-	return emptyDir / mi, storage / mi, storageNoSize, memStorage
+	return result
 }
 
 func generateContainers(containers []v1.Container) []ContainerItem {
 	var containerItems []ContainerItem
 	for _, container := range containers {
 		containerItems = append(containerItems, ContainerItem{
-			Name:                    container.Name,
-			RequestCPU:              container.Resources.Requests.Cpu().MilliValue(),
-			RequestMem:              container.Resources.Requests.Memory().Value() / mi,
-			RequestEphemeralStorate: container.Resources.Requests.StorageEphemeral().Value() / mi,
-			LimitCPU:                container.Resources.Limits.Cpu().MilliValue(),
-			LimitMem:                container.Resources.Limits.Memory().Value() / mi,
-			LimitEphemeralStorate:   container.Resources.Limits.StorageEphemeral().Value() / mi,
+			Name:         container.Name,
+			ResourceItem: generateResourceItem(container.Resources.Requests, container.Resources.Limits),
 		})
 	}
 	return containerItems
 }
 
-func GetNamespaces(clientset *kubernetes.Clientset) ([]string, error) {
-	if namespaceList, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{}); err != nil {
+// specReplicas returns the replicas of a spec, which defaults to 1 when it is not set, e.g. in manifests.
+func specReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// mergeLabels returns the labels overridden by newLabels.
+func mergeLabels(labels, newLabels map[string]string) map[string]string {
+	if len(labels) == 0 && len(newLabels) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(labels)+len(newLabels))
+	for key, value := range labels {
+		result[key] = value
+	}
+	for key, value := range newLabels {
+		result[key] = value
+	}
+	return result
+}
+
+// generatePodTemplateItem fills the volume and container fields of controllerItem from the pod template.
+func (info *clusterInfo) generatePodTemplateItem(controllerItem *ControllerItem, template v1.PodTemplateSpec) {
+	volumes := generateVolumeResult(template.Spec.Volumes, info.csiSizeAttributes)
+	if volumes.memStorage {
+		klog.Infof("memory EmptyDir, namespace: %q, %s: %q", controllerItem.Namespace, controllerItem.ControllerType, controllerItem.Controller)
+	}
+	controllerItem.EmptyDir = volumes.emptyDir
+	controllerItem.Storage = volumes.storage
+	controllerItem.StorageNoSize = volumes.storageNoSize
+	controllerItem.UnknownVolumes = volumes.unknownVolumes
+	info.generatePersistentStorage(controllerItem, template.Spec.Volumes)
+
+	controllerItem.Labels = mergeLabels(info.namespaceLabels[controllerItem.Namespace], template.Labels)
+	controllerItem.Container = generateContainers(template.Spec.Containers)
+	controllerItem.InitContainer = generateContainers(template.Spec.InitContainers)
+	controllerItem.EffectivePod, controllerItem.Total = info.generatePodResource(template.Spec, controllerItem.Replicas)
+}
+
+func GetNamespaces(ctx context.Context, clientset kubernetes.Interface) ([]string, error) {
+	if namespaceList, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{}); err != nil {
 		return nil, err
 	} else {
 		namespaces := make([]string, 0, len(namespaceList.Items))
@@ -121,22 +177,181 @@ func GetNamespaces(clientset *kubernetes.Clientset) ([]string, error) {
 	}
 }
 
-func GetControllerItems(clientset *kubernetes.Clientset, namespaces []string, debugInfo bool) ([]ControllerItem, error) {
+// Options are the settings of GetControllerItems.
+type Options struct {
+	// Namespaces are listed one by one, all namespaces are listed cluster wide when it is empty
+	Namespaces      []string
+	CustomResources []CustomResource
+	// CSISizeAttributes maps an inline CSI driver to the volumeAttributes keys holding the volume size, the driver has
+	// no storage when the keys are empty, defaultCSISizeAttributes is used for the drivers not in the map
+	CSISizeAttributes map[string][]string
+	// FailOnError aborts the collection at the first error, otherwise the errors are returned with the result
+	FailOnError bool
+	// Workers is the number of concurrent list requests
+	Workers int
+	// PageSize is the Limit of every list request, 0 lists everything at once
+	PageSize int64
+	// NamespaceLabels completes the labels of the controllers by the labels of their namespace
+	NamespaceLabels bool
+	// Metrics attaches the current usage of the pods from the metrics API, it requires the dynamic client
+	Metrics bool
+	// CountDaemonSetNodes counts the nodes matching the placement of the DaemonSets as their replicas instead of their
+	// status, e.g. for manifests, a DaemonSet has a single replica without nodes
+	CountDaemonSetNodes bool
+	// Nodes are counted instead of the nodes listed by the clientset when CountDaemonSetNodes is set, e.g. the nodes of
+	// the target cluster for manifests
+	Nodes     []v1.Node
+	DebugInfo bool
+}
+
+type collector struct {
+	kind    string
+	collect func(ctx context.Context, namespace string) ([]ControllerItem, error)
+}
+
+type collectTask struct {
+	namespace string
+	kind      string
+	collect   func(ctx context.Context, namespace string) ([]ControllerItem, error)
+	items     []ControllerItem
+	err       error
+}
+
+// runTasks runs every task with at most workers goroutines. The remaining tasks are canceled after the first error
+// when failOnError, which is returned.
+func runTasks(ctx context.Context, tasks []collectTask, workers int, failOnError bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var firstErr error
+	var once sync.Once
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers || worker == 0; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				task := &tasks[index]
+				if task.items, task.err = task.collect(ctx, task.namespace); task.err != nil && failOnError {
+					once.Do(func() {
+						firstErr = newCollectError(task.namespace, task.kind, task.err)
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+	for index := range tasks {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+	return firstErr
+}
+
+// GetControllerItems returns the controllers of every namespace, the namespaces and kinds are listed concurrently.
+// A namespace and kind which can not be collected is skipped and reported in the returned CollectError unless
+// FailOnError.
+func GetControllerItems(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, options Options) ([]ControllerItem, []CollectError, error) {
+	var collectErrors []CollectError
+	// addErrors returns the first error when the collection should be aborted
+	addErrors := func(errs ...CollectError) error {
+		for _, err := range errs {
+			if options.FailOnError {
+				return err
+			}
+			klog.Error(err)
+			collectErrors = append(collectErrors, err)
+		}
+		return nil
+	}
+	namespaces := options.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	info, infoErrors := getClusterInfo(ctx, clientset, namespaces, options)
+	if err := addErrors(infoErrors...); err != nil {
+		return nil, nil, err
+	}
+
+	collectors := []collector{
+		{"Deployment", func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			return getDeploymentItems(ctx, clientset, namespace, info, options)
+		}},
+		{"StatefulSet", func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			return getStatefulsetItems(ctx, clientset, namespace, info, options)
+		}},
+		{"DaemonSet", func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			return getDaemonsetItems(ctx, clientset, namespace, info, options)
+		}},
+		{"Job", func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			return getJobItems(ctx, clientset, namespace, info, options)
+		}},
+		{"CronJob", func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			return getCronJobItems(ctx, clientset, namespace, info, options)
+		}},
+	}
+	for _, customResource := range options.CustomResources {
+		collectors = append(collectors, collector{customResource.Resource + "." + customResource.Group, func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			return getCustomItems(ctx, dynamicClient, customResource, namespace, info, options)
+		}})
+	}
+	var tasks []collectTask
+	for _, namespace := range namespaces {
+		for _, collector := range collectors {
+			tasks = append(tasks, collectTask{namespace: namespace, kind: collector.kind, collect: collector.collect})
+		}
+	}
+	if err := runTasks(ctx, tasks, options.Workers, options.FailOnError); err != nil {
+		return nil, nil, err
+	}
 	var result []ControllerItem
-	if deployments, err := getDeploymentItems(clientset, namespaces, debugInfo); err != nil {
-		return result, err
-	} else {
-		result = append(result, deployments...)
+	for _, task := range tasks {
+		if task.err != nil {
+			if err := addErrors(newCollectError(task.namespace, task.kind, task.err)); err != nil {
+				return nil, nil, err
+			}
+		}
+		result = append(result, task.items...)
 	}
-	if statefulsets, err := getStatefulsetItems(clientset, namespaces, debugInfo); err != nil {
-		return result, err
-	} else {
-		result = append(result, statefulsets...)
+
+	// the pods and replicasets are resolved against the controllers collected above in their namespace
+	accountedKeys := accountedKeysByNamespace(result)
+	namespacePodControllers := make([]map[string]string, len(namespaces))
+	orphanTasks := make([]collectTask, 0, len(namespaces))
+	for index, namespace := range namespaces {
+		orphanTasks = append(orphanTasks, collectTask{namespace: namespace, kind: "Pod", collect: func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			items, podControllers, err := getOrphanItems(ctx, clientset, namespace, info, accountedKeys[namespace], options)
+			namespacePodControllers[index] = podControllers
+			return items, err
+		}})
 	}
-	if daemonsets, err := getDaemonsetItems(clientset, namespaces, debugInfo); err != nil {
-		return result, err
-	} else {
-		result = append(result, daemonsets...)
+	if err := runTasks(ctx, orphanTasks, options.Workers, options.FailOnError); err != nil {
+		return nil, nil, err
+	}
+	// podControllers holds the controllerKey of every running pod by its namespace/name, the usage is matched by it
+	podControllers := map[string]string{}
+	for index, task := range orphanTasks {
+		if task.err != nil {
+			if err := addErrors(newCollectError(task.namespace, task.kind, task.err)); err != nil {
+				return nil, nil, err
+			}
+		}
+		result = append(result, task.items...)
+		for pod, key := range namespacePodControllers[index] {
+			podControllers[pod] = key
+		}
+	}
+	if err := addAutoscalers(ctx, clientset, namespaces, result, options, addErrors); err != nil {
+		return nil, nil, err
+	}
+	if options.Metrics {
+		if err := addUsage(ctx, dynamicClient, namespaces, result, podControllers, options, addErrors); err != nil {
+			return nil, nil, err
+		}
 	}
-	return result, nil
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result, collectErrors, nil
 }
@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var cloneSetResource = schema.GroupVersionResource{Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets"}
+
+func int32Ptr(value int32) *int32 {
+	return &value
+}
+
+func testPodSpec(cpu, memory string) v1.PodSpec {
+	return v1.PodSpec{
+		Containers: []v1.Container{{
+			Name: "app",
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)},
+				Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)},
+			},
+		}},
+	}
+}
+
+func testTemplate(cpu, memory string) v1.PodTemplateSpec {
+	return v1.PodTemplateSpec{Spec: testPodSpec(cpu, memory)}
+}
+
+func objectMeta(name string, owner *metav1.OwnerReference) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{Name: name, Namespace: "default"}
+	if owner != nil {
+		meta.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return meta
+}
+
+func controllerRef(kind, name string) *metav1.OwnerReference {
+	isController := true
+	return &metav1.OwnerReference{Kind: kind, Name: name, Controller: &isController}
+}
+
+func testObjects() []runtime.Object {
+	kata := "kata"
+	overheadTemplate := testTemplate("100m", "128Mi")
+	overheadTemplate.Spec.RuntimeClassName = &kata
+	return []runtime.Object{
+		&nodev1.RuntimeClass{
+			ObjectMeta: metav1.ObjectMeta{Name: kata},
+			Overhead:   &nodev1.Overhead{PodFixed: v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m")}},
+		},
+		&storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "standard", Annotations: map[string]string{defaultStorageClassAnnotation: "true"}},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: objectMeta("web", nil),
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(3), Template: testTemplate("100m", "128Mi")},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: objectMeta("kata", nil),
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2), Template: overheadTemplate},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: objectMeta("db", nil),
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: int32Ptr(2),
+				Template: testTemplate("1", "1Gi"),
+				VolumeClaimTemplates: []v1.PersistentVolumeClaim{
+					{ObjectMeta: metav1.ObjectMeta{Name: "data"}, Spec: claimSpec(nil, "10Gi")},
+				},
+			},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: objectMeta("agent", nil),
+			Spec:       appsv1.DaemonSetSpec{Template: testTemplate("50m", "64Mi")},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3},
+		},
+		&batchv1.Job{
+			ObjectMeta: objectMeta("migrate", nil),
+			Spec:       batchv1.JobSpec{Parallelism: int32Ptr(4), Completions: int32Ptr(2), Template: testTemplate("500m", "256Mi")},
+		},
+		&batchv1.Job{
+			ObjectMeta: objectMeta("index", nil),
+			Spec:       batchv1.JobSpec{Parallelism: int32Ptr(4), Completions: int32Ptr(10), Template: testTemplate("500m", "256Mi")},
+			Status:     batchv1.JobStatus{Succeeded: 8},
+		},
+		&batchv1.Job{
+			ObjectMeta: objectMeta("seed", nil),
+			Spec:       batchv1.JobSpec{Completions: int32Ptr(1), Template: testTemplate("500m", "256Mi")},
+			Status:     batchv1.JobStatus{Succeeded: 1, CompletionTime: &metav1.Time{}},
+		},
+		&batchv1.Job{
+			ObjectMeta: objectMeta("import", nil),
+			Spec:       batchv1.JobSpec{Template: testTemplate("500m", "256Mi")},
+			Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: v1.ConditionTrue},
+			}},
+		},
+		&batchv1.CronJob{
+			ObjectMeta: objectMeta("backup", nil),
+			Spec: batchv1.CronJobSpec{
+				ConcurrencyPolicy: batchv1.ForbidConcurrent,
+				JobTemplate:       batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: testTemplate("200m", "256Mi")}},
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: objectMeta("backup-1", controllerRef("CronJob", "backup")),
+			Spec:       batchv1.JobSpec{Template: testTemplate("200m", "256Mi")},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: objectMeta("web-abc", controllerRef("Deployment", "web")),
+			Spec:       appsv1.ReplicaSetSpec{Replicas: int32Ptr(3), Template: testTemplate("100m", "128Mi")},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: objectMeta("orphan", nil),
+			Spec:       appsv1.ReplicaSetSpec{Replicas: int32Ptr(2), Template: testTemplate("100m", "128Mi")},
+		},
+		&v1.Pod{ObjectMeta: objectMeta("web-abc-1", controllerRef("ReplicaSet", "web-abc")), Spec: testPodSpec("100m", "128Mi")},
+		&v1.Pod{ObjectMeta: objectMeta("orphan-1", controllerRef("ReplicaSet", "orphan")), Spec: testPodSpec("100m", "128Mi")},
+		&v1.Pod{ObjectMeta: objectMeta("backup-1-x", controllerRef("Job", "backup-1")), Spec: testPodSpec("200m", "256Mi")},
+		&v1.Pod{ObjectMeta: objectMeta("db-0", controllerRef("StatefulSet", "db")), Spec: testPodSpec("1", "1Gi")},
+		&v1.Pod{ObjectMeta: objectMeta("clone-x", controllerRef("CloneSet", "clone")), Spec: testPodSpec("300m", "512Mi")},
+		&v1.Pod{ObjectMeta: objectMeta("bare", nil), Spec: testPodSpec("150m", "100Mi")},
+		&v1.Pod{
+			ObjectMeta: objectMeta("done", nil),
+			Spec:       testPodSpec("150m", "100Mi"),
+			Status:     v1.PodStatus{Phase: v1.PodSucceeded},
+		},
+	}
+}
+
+func testCustomObjects() []runtime.Object {
+	cloneSet := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.kruise.io/v1alpha1",
+		"kind":       "CloneSet",
+		"metadata":   map[string]interface{}{"name": "clone", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"replicas": int64(5),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name":      "app",
+						"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "300m", "memory": "512Mi"}},
+					}},
+				},
+			},
+		},
+	}}
+	return []runtime.Object{cloneSet}
+}
+
+func newTestClients(objects []runtime.Object) (*fake.Clientset, *dynamicfake.FakeDynamicClient) {
+	clientset := fake.NewSimpleClientset(objects...)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{cloneSetResource: "CloneSetList"}, testCustomObjects()...)
+	return clientset, dynamicClient
+}
+
+func testOptions(namespaces ...string) Options {
+	return Options{
+		Namespaces: namespaces,
+		CustomResources: []CustomResource{
+			{Group: cloneSetResource.Group, Version: cloneSetResource.Version, Resource: cloneSetResource.Resource},
+		},
+		Workers:  4,
+		PageSize: 2,
+	}
+}
+
+func findControllerItem(items []ControllerItem, controllerType, name string) *ControllerItem {
+	for i := range items {
+		if items[i].ControllerType == controllerType && items[i].Controller == name {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+func TestGetControllerItems(t *testing.T) {
+	tests := []struct {
+		controllerType string
+		name           string
+		replicas       int32
+		requestCPU     string
+		totalCPU       string
+	}{
+		{"Deployment", "web", 3, "100m", "300m"},
+		{"Deployment", "kata", 2, "350m", "700m"},
+		{"Statefulset", "db", 2, "1", "2"},
+		{"Daemonset", "agent", 3, "50m", "150m"},
+		{"Job", "migrate", 2, "500m", "1"},
+		// a job runs at most its remaining completions
+		{"Job", "index", 2, "500m", "1"},
+		// the finished jobs run no pod
+		{"Job", "seed", 0, "500m", "0"},
+		{"Job", "import", 0, "500m", "0"},
+		{"CronJob", "backup", 1, "200m", "200m"},
+		{"CloneSet", "clone", 5, "300m", "1500m"},
+		{"ReplicaSet", "orphan", 2, "100m", "200m"},
+		{"Pod", "bare", 1, "150m", "150m"},
+	}
+	for _, namespaces := range [][]string{nil, {"default"}} {
+		clientset, dynamicClient := newTestClients(testObjects())
+		items, collectErrors, err := GetControllerItems(context.Background(), clientset, dynamicClient, testOptions(namespaces...))
+		if err != nil {
+			t.Fatalf("GetControllerItems() error = %v", err)
+		}
+		if len(collectErrors) != 0 {
+			t.Errorf("GetControllerItems() collectErrors = %v", collectErrors)
+		}
+		if len(items) != len(tests) {
+			t.Errorf("GetControllerItems() returns %d items, want %d: %+v", len(items), len(tests), items)
+		}
+		for _, test := range tests {
+			item := findControllerItem(items, test.controllerType, test.name)
+			if item == nil {
+				t.Errorf("%s %q not found", test.controllerType, test.name)
+				continue
+			}
+			if item.Replicas != test.replicas {
+				t.Errorf("%s %q replicas = %d, want %d", test.controllerType, test.name, item.Replicas, test.replicas)
+			}
+			if item.EffectivePod.RequestCPU.Cmp(resource.MustParse(test.requestCPU)) != 0 {
+				t.Errorf("%s %q pod request cpu = %s, want %s", test.controllerType, test.name, item.EffectivePod.RequestCPU.String(), test.requestCPU)
+			}
+			if item.Total.RequestCPU.Cmp(resource.MustParse(test.totalCPU)) != 0 {
+				t.Errorf("%s %q total request cpu = %s, want %s", test.controllerType, test.name, item.Total.RequestCPU.String(), test.totalCPU)
+			}
+		}
+		db := findControllerItem(items, "Statefulset", "db")
+		if db != nil && !equalStorageClassItems(db.PersistentStorage, []StorageClassItem{
+			{StorageClass: "standard", Claims: 2, Request: resource.MustParse("20Gi")},
+		}) {
+			t.Errorf("Statefulset %q persistent storage = %+v", "db", db.PersistentStorage)
+		}
+	}
+}
+
+func TestGetControllerItemsErrors(t *testing.T) {
+	forbidden := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "statefulsets"}, "", errors.New("rbac"))
+	}
+
+	clientset, dynamicClient := newTestClients(testObjects())
+	clientset.PrependReactor("list", "statefulsets", forbidden)
+	items, collectErrors, err := GetControllerItems(context.Background(), clientset, dynamicClient, testOptions("default"))
+	if err != nil {
+		t.Fatalf("GetControllerItems() error = %v", err)
+	}
+	if len(collectErrors) != 1 || collectErrors[0].Namespace != "default" || collectErrors[0].Kind != "StatefulSet" {
+		t.Errorf("GetControllerItems() collectErrors = %+v, want the statefulsets of namespace default", collectErrors)
+	}
+	if findControllerItem(items, "Deployment", "web") == nil {
+		t.Errorf("GetControllerItems() does not keep going after an error")
+	}
+	// the pod of the statefulset is reported as a bare pod as its owner is unknown
+	if findControllerItem(items, "Pod", "db-0") == nil {
+		t.Errorf("pod %q of the failed statefulset not found", "db-0")
+	}
+
+	clientset, dynamicClient = newTestClients(testObjects())
+	clientset.PrependReactor("list", "statefulsets", forbidden)
+	options := testOptions("default")
+	options.FailOnError = true
+	if _, _, err := GetControllerItems(context.Background(), clientset, dynamicClient, options); err == nil {
+		t.Errorf("GetControllerItems() error = nil with FailOnError")
+	}
+
+	// the runtimeclasses and storageclasses which can't be listed are warnings with their fallbacks
+	clientset, dynamicClient = newTestClients(testObjects())
+	clientset.PrependReactor("list", "runtimeclasses", forbidden)
+	clientset.PrependReactor("list", "storageclasses", forbidden)
+	items, collectErrors, err = GetControllerItems(context.Background(), clientset, dynamicClient, options)
+	if err != nil || len(collectErrors) != 0 {
+		t.Fatalf("GetControllerItems() collectErrors = %+v, error = %v, want none for the forbidden cluster classes", collectErrors, err)
+	}
+	if findControllerItem(items, "Deployment", "web") == nil {
+		t.Errorf("Deployment %q not found", "web")
+	}
+}
+
+func TestAccountedKeysByNamespace(t *testing.T) {
+	keys := accountedKeysByNamespace([]ControllerItem{
+		{Namespace: "default", ControllerType: "Deployment", Controller: "web"},
+		{Namespace: "shop", ControllerType: "Statefulset", Controller: "db"},
+	})
+	// the controllers of another namespace are not resolved
+	if resolver := newOwnerResolver(keys["default"]); !resolver.accounted["deployment/default/web"] || resolver.accounted["statefulset/shop/db"] {
+		t.Errorf("default keys = %v", keys["default"])
+	}
+	if len(keys[metav1.NamespaceAll]) != 2 {
+		t.Errorf("all namespaces keys = %v, want every controller", keys[metav1.NamespaceAll])
+	}
+}
+
+func TestPodResources(t *testing.T) {
+	sidecar := v1.ContainerRestartPolicyAlways
+	container := func(name, cpu string, restartPolicy *v1.ContainerRestartPolicy) v1.Container {
+		return v1.Container{
+			Name:          name,
+			RestartPolicy: restartPolicy,
+			Resources:     v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}},
+		}
+	}
+	tests := []struct {
+		name     string
+		podSpec  v1.PodSpec
+		overhead v1.ResourceList
+		request  string
+	}{
+		{
+			name:    "containers are summed",
+			podSpec: v1.PodSpec{Containers: []v1.Container{container("a", "100m", nil), container("b", "200m", nil)}},
+			request: "300m",
+		},
+		{
+			name: "init container is the max",
+			podSpec: v1.PodSpec{
+				InitContainers: []v1.Container{container("init", "1", nil)},
+				Containers:     []v1.Container{container("a", "100m", nil), container("b", "200m", nil)},
+			},
+			request: "1",
+		},
+		{
+			name: "sidecars are added to containers and later init containers",
+			podSpec: v1.PodSpec{
+				InitContainers: []v1.Container{container("sidecar", "100m", &sidecar), container("init", "500m", nil)},
+				Containers:     []v1.Container{container("a", "200m", nil)},
+			},
+			request: "600m",
+		},
+		{
+			name:     "overhead",
+			podSpec:  v1.PodSpec{Containers: []v1.Container{container("a", "100m", nil)}},
+			overhead: v1.ResourceList{v1.ResourceCPU: resource.MustParse("50m")},
+			request:  "150m",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := podRequests(test.podSpec, test.overhead)
+			if requests.Cpu().Cmp(resource.MustParse(test.request)) != 0 {
+				t.Errorf("podRequests() cpu = %s, want %s", requests.Cpu().String(), test.request)
+			}
+			// only limits are set, so requests are defaulted from them and both match
+			limits := podLimits(test.podSpec, test.overhead)
+			if limits.Cpu().Cmp(resource.MustParse(test.request)) != 0 {
+				t.Errorf("podLimits() cpu = %s, want %s", limits.Cpu().String(), test.request)
+			}
+		})
+	}
+}
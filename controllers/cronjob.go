@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"context"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// cronJobReplicas estimates the number of pods a cronjob runs concurrently: Forbid and Replace never run more than
+// one job at a time, Allow is estimated by the jobs currently active.
+func cronJobReplicas(cronJob batchv1.CronJob) int32 {
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+		return 0
+	}
+	var jobs int32 = 1
+	if cronJob.Spec.ConcurrencyPolicy == batchv1.AllowConcurrent && len(cronJob.Status.Active) > 1 {
+		jobs = int32(len(cronJob.Status.Active))
+	}
+	return jobs * jobParallelism(cronJob.Spec.JobTemplate.Spec)
+}
+
+func getCronJobItems(ctx context.Context, clientset kubernetes.Interface, namespace string, info *clusterInfo, options Options) ([]ControllerItem, error) {
+	var result []ControllerItem
+	err := listPages(ctx, "cronjob", namespace, options, clientset.BatchV1().CronJobs(namespace).List, func(controllers *batchv1.CronJobList) error {
+		for _, controller := range controllers.Items {
+			controllerItem := ControllerItem{
+				Namespace:      controller.Namespace,
+				ControllerType: TypeCronJob,
+				Controller:     controller.Name,
+				Replicas:       cronJobReplicas(controller),
+			}
+			info.generatePodTemplateItem(&controllerItem, controller.Spec.JobTemplate.Spec.Template)
+			result = append(result, controllerItem)
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultTemplatePath = "{.spec.template}"
+	defaultReplicasPath = "{.spec.replicas}"
+)
+
+// CustomResource describes a custom workload resource which embeds a PodTemplateSpec, e.g. argo rollouts.
+type CustomResource struct {
+	Group    string `json:"group" mapstructure:"group"`
+	Version  string `json:"version" mapstructure:"version"`
+	Resource string `json:"resource" mapstructure:"resource"`
+	// TemplatePath is the JSONPath of the pod template, default {.spec.template}
+	TemplatePath string `json:"templatePath,omitempty" mapstructure:"templatePath"`
+	// ReplicasPath is the JSONPath of the replicas, default {.spec.replicas}, 1 replica is used when it is not found
+	ReplicasPath string `json:"replicasPath,omitempty" mapstructure:"replicasPath"`
+}
+
+func (customResource CustomResource) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: customResource.Group, Version: customResource.Version, Resource: customResource.Resource}
+}
+
+func parseJsonPath(name, path, defaultPath string) (*jsonpath.JSONPath, error) {
+	if path == "" {
+		path = defaultPath
+	}
+	parser := jsonpath.New(name).AllowMissingKeys(true)
+	if err := parser.Parse(path); err != nil {
+		return nil, fmt.Errorf("parse jsonpath %q of %s failed: %v", path, name, err)
+	}
+	return parser, nil
+}
+
+// findJsonPath returns the first value matched by parser, nil if nothing matches.
+func findJsonPath(parser *jsonpath.JSONPath, object map[string]interface{}) (interface{}, error) {
+	results, err := parser.FindResults(object)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() {
+				return value.Interface(), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func getCustomReplicas(parser *jsonpath.JSONPath, object *unstructured.Unstructured) (int32, error) {
+	value, err := findJsonPath(parser, object.Object)
+	if err != nil {
+		return 0, err
+	}
+	switch replicas := value.(type) {
+	case nil:
+		return 1, nil
+	case int64:
+		return int32(replicas), nil
+	case float64:
+		return int32(replicas), nil
+	default:
+		return 0, fmt.Errorf("replicas %v is not a number", value)
+	}
+}
+
+func getCustomTemplate(parser *jsonpath.JSONPath, object *unstructured.Unstructured) (*v1.PodTemplateSpec, error) {
+	value, err := findJsonPath(parser, object.Object)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	templateObject, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("pod template %v is not an object", value)
+	}
+	template := &v1.PodTemplateSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(templateObject, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func getCustomItems(ctx context.Context, dynamicClient dynamic.Interface, customResource CustomResource, namespace string, info *clusterInfo, options Options) ([]ControllerItem, error) {
+	gvr := customResource.GroupVersionResource()
+	templateParser, err := parseJsonPath(gvr.String(), customResource.TemplatePath, defaultTemplatePath)
+	if err != nil {
+		return nil, err
+	}
+	replicasParser, err := parseJsonPath(gvr.String(), customResource.ReplicasPath, defaultReplicasPath)
+	if err != nil {
+		return nil, err
+	}
+	var result []ControllerItem
+	err = listPages(ctx, gvr.Resource, namespace, options, dynamicClient.Resource(gvr).Namespace(namespace).List, func(controllers *unstructured.UnstructuredList) error {
+		for i := range controllers.Items {
+			controller := &controllers.Items[i]
+			template, err := getCustomTemplate(templateParser, controller)
+			if err != nil {
+				return fmt.Errorf("get pod template of %s %s/%s failed: %v", gvr.Resource, controller.GetNamespace(), controller.GetName(), err)
+			} else if template == nil {
+				klog.Infof("no pod template, namespace: %q, %s: %q", controller.GetNamespace(), controller.GetKind(), controller.GetName())
+				continue
+			}
+			replicas, err := getCustomReplicas(replicasParser, controller)
+			if err != nil {
+				return fmt.Errorf("get replicas of %s %s/%s failed: %v", gvr.Resource, controller.GetNamespace(), controller.GetName(), err)
+			}
+			controllerItem := ControllerItem{
+				Namespace:      controller.GetNamespace(),
+				ControllerType: controller.GetKind(),
+				Controller:     controller.GetName(),
+				Replicas:       replicas,
+			}
+			info.generatePodTemplateItem(&controllerItem, *template)
+			result = append(result, controllerItem)
+		}
+		return nil
+	})
+	return result, err
+}
@@ -2,46 +2,38 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/klog/v2"
 )
 
-func getDaemonsetItems(clientset *kubernetes.Clientset, namespaces []string, debugInfo bool) ([]ControllerItem, error) {
+// daemonSetReplicas returns the number of nodes controller runs on: its status, or the nodes matching placement when
+// options.CountDaemonSetNodes is set, 1 when there is no node to count.
+func daemonSetReplicas(controller appsv1.DaemonSet, placement *PlacementItem, info *clusterInfo, options Options) int32 {
+	if !options.CountDaemonSetNodes {
+		return controller.Status.DesiredNumberScheduled
+	} else if len(info.nodes) == 0 {
+		return 1
+	}
+	return placement.countNodes(info.nodes)
+}
+
+func getDaemonsetItems(ctx context.Context, clientset kubernetes.Interface, namespace string, info *clusterInfo, options Options) ([]ControllerItem, error) {
 	var result []ControllerItem
-	for _, namespace := range namespaces {
-		controllerClient := clientset.AppsV1().DaemonSets(namespace)
-		controllers, err := controllerClient.List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			return nil, nil
-		}
-		if debugInfo {
-			if controllerJson, err := json.Marshal(controllers); err != nil {
-				return nil, nil
-			} else {
-				klog.Infof("namespaces %s statefulset:\n%s", namespace, controllerJson)
-			}
-		}
+	err := listPages(ctx, "daemonset", namespace, options, clientset.AppsV1().DaemonSets(namespace).List, func(controllers *appsv1.DaemonSetList) error {
 		for _, controller := range controllers.Items {
+			placement := newPlacementItem(controller.Spec.Template.Spec)
 			controllerItem := ControllerItem{
 				Namespace:      controller.Namespace,
-				ControllerType: "Daemonset",
+				ControllerType: TypeDaemonSet,
 				Controller:     controller.Name,
-				Replicas:       1,
-			}
-			emptyDir, storage, storageNoSize, memStorage := generateVolumeResult(controller.Spec.Template.Spec.Volumes)
-			if memStorage {
-				klog.Infof("memory EmptyDir, namespace: %q, %s: %q", controllerItem.Namespace, controllerItem.ControllerType, controllerItem.Controller)
+				Replicas:       daemonSetReplicas(controller, placement, info, options),
+				Placement:      placement,
 			}
-			controllerItem.EmptyDir = emptyDir
-			controllerItem.Storage = storage
-			controllerItem.StorageNoSize = storageNoSize
-
-			controllerItem.Container = generateContainers(controller.Spec.Template.Spec.Containers)
-			controllerItem.InitContainer = generateContainers(controller.Spec.Template.Spec.InitContainers)
+			info.generatePodTemplateItem(&controllerItem, controller.Spec.Template)
+			controllerItem.setDaemonSetRollout(controller.Spec.UpdateStrategy)
 			result = append(result, controllerItem)
 		}
-	}
-	return result, nil
+		return nil
+	})
+	return result, err
 }
@@ -2,46 +2,25 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/klog/v2"
 )
 
-func getDeploymentItems(clientset *kubernetes.Clientset, namespaces []string, debugInfo bool) ([]ControllerItem, error) {
+func getDeploymentItems(ctx context.Context, clientset kubernetes.Interface, namespace string, info *clusterInfo, options Options) ([]ControllerItem, error) {
 	var result []ControllerItem
-	for _, namespace := range namespaces {
-		controllerClient := clientset.AppsV1().Deployments(namespace)
-		controllers, err := controllerClient.List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			return nil, nil
-		}
-		if debugInfo {
-			if controllerJson, err := json.Marshal(controllers); err != nil {
-				return nil, nil
-			} else {
-				klog.Infof("namespaces %s statefulset:\n%s", namespace, controllerJson)
-			}
-		}
+	err := listPages(ctx, "deployment", namespace, options, clientset.AppsV1().Deployments(namespace).List, func(controllers *appsv1.DeploymentList) error {
 		for _, controller := range controllers.Items {
 			controllerItem := ControllerItem{
 				Namespace:      controller.Namespace,
-				ControllerType: "Deployment",
+				ControllerType: TypeDeployment,
 				Controller:     controller.Name,
-				Replicas:       *controller.Spec.Replicas,
+				Replicas:       specReplicas(controller.Spec.Replicas),
 			}
-			emptyDir, storage, storageNoSize, memStorage := generateVolumeResult(controller.Spec.Template.Spec.Volumes)
-			if memStorage {
-				klog.Infof("memory EmptyDir, namespace: %q, %s: %q", controllerItem.Namespace, controllerItem.ControllerType, controllerItem.Controller)
-			}
-			controllerItem.EmptyDir = emptyDir
-			controllerItem.Storage = storage
-			controllerItem.StorageNoSize = storageNoSize
-
-			controllerItem.Container = generateContainers(controller.Spec.Template.Spec.Containers)
-			controllerItem.InitContainer = generateContainers(controller.Spec.Template.Spec.InitContainers)
+			info.generatePodTemplateItem(&controllerItem, controller.Spec.Template)
+			controllerItem.setDeploymentRollout(controller.Spec.Strategy)
 			result = append(result, controllerItem)
 		}
-	}
-	return result, nil
+		return nil
+	})
+	return result, err
 }
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"sort"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ContainerDiff is the change of a container, Delta is New minus Old.
+type ContainerDiff struct {
+	ContainerType string       `json:"containerType"`
+	Name          string       `json:"name"`
+	Old           ResourceItem `json:"old"`
+	New           ResourceItem `json:"new"`
+	Delta         ResourceItem `json:"delta"`
+}
+
+// ControllerDiff is the change of a controller, only the changed containers are listed.
+type ControllerDiff struct {
+	Source         string          `json:"source,omitempty"`
+	Namespace      string          `json:"namespace"`
+	ControllerType string          `json:"controllerType"`
+	Controller     string          `json:"controller"`
+	Change         string          `json:"change"`
+	OldReplicas    int32           `json:"oldReplicas"`
+	NewReplicas    int32           `json:"newReplicas"`
+	Containers     []ContainerDiff `json:"containers,omitempty"`
+	OldTotal       ResourceItem    `json:"oldTotal"`
+	NewTotal       ResourceItem    `json:"newTotal"`
+	TotalDelta     ResourceItem    `json:"totalDelta"`
+}
+
+// NamespaceDiff aggregates the totals of all controllers of a namespace.
+type NamespaceDiff struct {
+	Namespace  string       `json:"namespace"`
+	Added      int          `json:"added"`
+	Removed    int          `json:"removed"`
+	Changed    int          `json:"changed"`
+	OldTotal   ResourceItem `json:"oldTotal"`
+	NewTotal   ResourceItem `json:"newTotal"`
+	TotalDelta ResourceItem `json:"totalDelta"`
+}
+
+// ReportDiff is the difference between two reports, unchanged controllers and namespaces are omitted.
+type ReportDiff struct {
+	Controllers []ControllerDiff `json:"controllers,omitempty"`
+	Namespaces  []NamespaceDiff  `json:"namespaces,omitempty"`
+}
+
+func addResourceItem(item *ResourceItem, newItem ResourceItem) {
+	item.RequestCPU.Add(newItem.RequestCPU)
+	item.RequestMem.Add(newItem.RequestMem)
+	item.RequestEphemeralStorate.Add(newItem.RequestEphemeralStorate)
+	item.LimitCPU.Add(newItem.LimitCPU)
+	item.LimitMem.Add(newItem.LimitMem)
+	item.LimitEphemeralStorate.Add(newItem.LimitEphemeralStorate)
+}
+
+// subtractResourceItem returns newItem minus oldItem.
+func subtractResourceItem(newItem, oldItem ResourceItem) ResourceItem {
+	result := ResourceItem{
+		RequestCPU:              newItem.RequestCPU.DeepCopy(),
+		RequestMem:              newItem.RequestMem.DeepCopy(),
+		RequestEphemeralStorate: newItem.RequestEphemeralStorate.DeepCopy(),
+		LimitCPU:                newItem.LimitCPU.DeepCopy(),
+		LimitMem:                newItem.LimitMem.DeepCopy(),
+		LimitEphemeralStorate:   newItem.LimitEphemeralStorate.DeepCopy(),
+	}
+	result.RequestCPU.Sub(oldItem.RequestCPU)
+	result.RequestMem.Sub(oldItem.RequestMem)
+	result.RequestEphemeralStorate.Sub(oldItem.RequestEphemeralStorate)
+	result.LimitCPU.Sub(oldItem.LimitCPU)
+	result.LimitMem.Sub(oldItem.LimitMem)
+	result.LimitEphemeralStorate.Sub(oldItem.LimitEphemeralStorate)
+	return result
+}
+
+func (item ResourceItem) IsZero() bool {
+	return item.RequestCPU.IsZero() && item.RequestMem.IsZero() && item.RequestEphemeralStorate.IsZero() &&
+		item.LimitCPU.IsZero() && item.LimitMem.IsZero() && item.LimitEphemeralStorate.IsZero()
+}
+
+func containerKey(containerType, name string) string {
+	return containerType + "/" + name
+}
+
+// containerItems returns the containers of controllerItem by containerKey, and the keys in order.
+func containerItems(controllerItem ControllerItem) (map[string]ContainerDiff, []string) {
+	containers := map[string]ContainerDiff{}
+	var keys []string
+	add := func(containerType string, items []ContainerItem) {
+		for _, item := range items {
+			key := containerKey(containerType, item.Name)
+			containers[key] = ContainerDiff{ContainerType: containerType, Name: item.Name, New: item.ResourceItem}
+			keys = append(keys, key)
+		}
+	}
+	add("initContainer", controllerItem.InitContainer)
+	add("container", controllerItem.Container)
+	return containers, keys
+}
+
+// diffContainers returns the changed containers, the removed ones follow the containers of newItem.
+func diffContainers(oldItem, newItem ControllerItem) []ContainerDiff {
+	oldContainers, oldKeys := containerItems(oldItem)
+	newContainers, newKeys := containerItems(newItem)
+	var result []ContainerDiff
+	for _, key := range newKeys {
+		container := newContainers[key]
+		container.Old = oldContainers[key].New
+		container.Delta = subtractResourceItem(container.New, container.Old)
+		if !container.Delta.IsZero() {
+			result = append(result, container)
+		}
+	}
+	for _, key := range oldKeys {
+		if _, ok := newContainers[key]; !ok {
+			container := oldContainers[key]
+			container.Old, container.New = container.New, ResourceItem{}
+			container.Delta = subtractResourceItem(container.New, container.Old)
+			if !container.Delta.IsZero() {
+				result = append(result, container)
+			}
+		}
+	}
+	return result
+}
+
+// diffKey returns the key a controller is matched by in two reports, the same controller of several sources, e.g.
+// kustomize overlays, is compared source by source.
+func diffKey(item ControllerItem) string {
+	return item.Source + "/" + controllerKey(item.ControllerType, item.Namespace, item.Controller)
+}
+
+// DiffReports compares the controllers of oldReport and newReport, controllers are matched by source, namespace, type
+// and name.
+func DiffReports(oldReport, newReport Report) ReportDiff {
+	var result ReportDiff
+	oldItems := map[string]ControllerItem{}
+	for _, item := range oldReport.Responses {
+		oldItems[diffKey(item)] = item
+	}
+	namespaces := map[string]*NamespaceDiff{}
+	namespaceDiff := func(namespace string) *NamespaceDiff {
+		if _, ok := namespaces[namespace]; !ok {
+			namespaces[namespace] = &NamespaceDiff{Namespace: namespace}
+		}
+		return namespaces[namespace]
+	}
+	seen := map[string]bool{}
+	for _, newItem := range newReport.Responses {
+		key := diffKey(newItem)
+		seen[key] = true
+		oldItem, ok := oldItems[key]
+		controllerDiff := ControllerDiff{
+			Source:         newItem.Source,
+			Namespace:      newItem.Namespace,
+			ControllerType: newItem.ControllerType,
+			Controller:     newItem.Controller,
+			OldReplicas:    oldItem.Replicas,
+			NewReplicas:    newItem.Replicas,
+			Containers:     diffContainers(oldItem, newItem),
+			OldTotal:       oldItem.Total,
+			NewTotal:       newItem.Total,
+			TotalDelta:     subtractResourceItem(newItem.Total, oldItem.Total),
+		}
+		namespace := namespaceDiff(newItem.Namespace)
+		addResourceItem(&namespace.OldTotal, oldItem.Total)
+		addResourceItem(&namespace.NewTotal, newItem.Total)
+		if !ok {
+			controllerDiff.Change = ChangeAdded
+			namespace.Added++
+		} else if controllerDiff.OldReplicas != controllerDiff.NewReplicas || len(controllerDiff.Containers) > 0 || !controllerDiff.TotalDelta.IsZero() {
+			controllerDiff.Change = ChangeChanged
+			namespace.Changed++
+		} else {
+			continue
+		}
+		result.Controllers = append(result.Controllers, controllerDiff)
+	}
+	for _, oldItem := range oldReport.Responses {
+		if seen[diffKey(oldItem)] {
+			continue
+		}
+		result.Controllers = append(result.Controllers, ControllerDiff{
+			Source:         oldItem.Source,
+			Namespace:      oldItem.Namespace,
+			ControllerType: oldItem.ControllerType,
+			Controller:     oldItem.Controller,
+			Change:         ChangeRemoved,
+			OldReplicas:    oldItem.Replicas,
+			Containers:     diffContainers(oldItem, ControllerItem{}),
+			OldTotal:       oldItem.Total,
+			TotalDelta:     subtractResourceItem(ResourceItem{}, oldItem.Total),
+		})
+		namespace := namespaceDiff(oldItem.Namespace)
+		addResourceItem(&namespace.OldTotal, oldItem.Total)
+		namespace.Removed++
+	}
+	sort.SliceStable(result.Controllers, func(i, j int) bool {
+		return result.Controllers[i].Namespace < result.Controllers[j].Namespace
+	})
+	for _, namespace := range namespaces {
+		namespace.TotalDelta = subtractResourceItem(namespace.NewTotal, namespace.OldTotal)
+		if namespace.Added > 0 || namespace.Removed > 0 || namespace.Changed > 0 || !namespace.TotalDelta.IsZero() {
+			result.Namespaces = append(result.Namespaces, *namespace)
+		}
+	}
+	sort.Slice(result.Namespaces, func(i, j int) bool {
+		return result.Namespaces[i].Namespace < result.Namespaces[j].Namespace
+	})
+	return result
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func testDiffItem(namespace, controllerType, controller string, replicas int32, containers map[string]string) ControllerItem {
+	item := ControllerItem{Namespace: namespace, ControllerType: controllerType, Controller: controller, Replicas: replicas}
+	for name, cpu := range containers {
+		item.Container = append(item.Container, ContainerItem{Name: name, ResourceItem: ResourceItem{RequestCPU: resource.MustParse(cpu)}})
+		total := resource.MustParse(cpu)
+		total.Mul(int64(replicas))
+		item.Total.RequestCPU.Add(total)
+	}
+	return item
+}
+
+func TestDiffReports(t *testing.T) {
+	oldReport := Report{Responses: []ControllerItem{
+		testDiffItem("default", "Deployment", "web", 2, map[string]string{"app": "500m"}),
+		testDiffItem("default", "Deployment", "same", 1, map[string]string{"app": "1"}),
+		testDiffItem("legacy", "Deployment", "old", 1, map[string]string{"app": "100m"}),
+	}}
+	newReport := Report{Responses: []ControllerItem{
+		testDiffItem("default", "Deployment", "web", 3, map[string]string{"app": "250m"}),
+		testDiffItem("default", "Deployment", "same", 1, map[string]string{"app": "1"}),
+		testDiffItem("default", "Job", "migrate", 1, map[string]string{"migrate": "1"}),
+	}}
+	diff := DiffReports(oldReport, newReport)
+
+	want := []struct {
+		controller  string
+		change      string
+		containers  int
+		deltaCPU    string
+		oldReplicas int32
+		newReplicas int32
+	}{
+		{"web", ChangeChanged, 1, "-250m", 2, 3},
+		{"migrate", ChangeAdded, 1, "1", 0, 1},
+		{"old", ChangeRemoved, 1, "-100m", 1, 0},
+	}
+	if len(diff.Controllers) != len(want) {
+		t.Fatalf("DiffReports() returns %d controllers, want %d: %+v", len(diff.Controllers), len(want), diff.Controllers)
+	}
+	for index, test := range want {
+		controllerDiff := diff.Controllers[index]
+		if controllerDiff.Controller != test.controller || controllerDiff.Change != test.change ||
+			controllerDiff.OldReplicas != test.oldReplicas || controllerDiff.NewReplicas != test.newReplicas {
+			t.Errorf("controllers[%d] = %s %s %d->%d, want %s %s %d->%d", index, controllerDiff.Controller, controllerDiff.Change,
+				controllerDiff.OldReplicas, controllerDiff.NewReplicas, test.controller, test.change, test.oldReplicas, test.newReplicas)
+		}
+		if len(controllerDiff.Containers) != test.containers {
+			t.Errorf("controllers[%d] has %d changed containers, want %d", index, len(controllerDiff.Containers), test.containers)
+		} else if got := controllerDiff.Containers[0].Delta.RequestCPU; got.Cmp(resource.MustParse(test.deltaCPU)) != 0 {
+			t.Errorf("controllers[%d] container delta = %s, want %s", index, got.String(), test.deltaCPU)
+		}
+	}
+	// web: 3 * 250m - 2 * 500m
+	if got := diff.Controllers[0].TotalDelta.RequestCPU; got.Cmp(resource.MustParse("-250m")) != 0 {
+		t.Errorf("web total delta = %s, want -250m", got.String())
+	}
+
+	if len(diff.Namespaces) != 2 {
+		t.Fatalf("DiffReports() returns %d namespaces, want 2", len(diff.Namespaces))
+	}
+	defaultDiff, legacyDiff := diff.Namespaces[0], diff.Namespaces[1]
+	if defaultDiff.Namespace != "default" || defaultDiff.Added != 1 || defaultDiff.Changed != 1 || defaultDiff.Removed != 0 {
+		t.Errorf("namespaces[0] = %+v", defaultDiff)
+	}
+	if got := defaultDiff.TotalDelta.RequestCPU; got.Cmp(resource.MustParse("750m")) != 0 {
+		t.Errorf("default total delta = %s, want 750m", got.String())
+	}
+	if legacyDiff.Namespace != "legacy" || legacyDiff.Removed != 1 {
+		t.Errorf("namespaces[1] = %+v", legacyDiff)
+	}
+
+	if diff := DiffReports(oldReport, oldReport); len(diff.Controllers) != 0 || len(diff.Namespaces) != 0 {
+		t.Errorf("DiffReports() of the same report = %+v, want no changes", diff)
+	}
+}
+
+func TestDiffReportsSources(t *testing.T) {
+	testSourceItem := func(source string, replicas int32) ControllerItem {
+		item := testDiffItem("default", "Deployment", "web", replicas, map[string]string{"app": "500m"})
+		item.Source = source
+		return item
+	}
+	oldReport := Report{Responses: []ControllerItem{testSourceItem("overlays/staging", 2), testSourceItem("overlays/prod", 4)}}
+	newReport := Report{Responses: []ControllerItem{testSourceItem("overlays/staging", 2), testSourceItem("overlays/prod", 5)}}
+	diff := DiffReports(oldReport, newReport)
+
+	// every overlay is compared with its own old item
+	if len(diff.Controllers) != 1 {
+		t.Fatalf("DiffReports() returns %d controllers, want 1: %+v", len(diff.Controllers), diff.Controllers)
+	}
+	if controllerDiff := diff.Controllers[0]; controllerDiff.Source != "overlays/prod" || controllerDiff.Change != ChangeChanged ||
+		controllerDiff.OldReplicas != 4 || controllerDiff.NewReplicas != 5 {
+		t.Errorf("controllers[0] = %+v, want the prod overlay scaled from 4 to 5", controllerDiff)
+	}
+	if len(diff.Namespaces) != 1 {
+		t.Fatalf("DiffReports() returns %d namespaces, want 1", len(diff.Namespaces))
+	}
+	namespaceDiff := diff.Namespaces[0]
+	if got := namespaceDiff.OldTotal.RequestCPU; got.Cmp(resource.MustParse("3")) != 0 {
+		t.Errorf("default old total = %s, want 3", got.String())
+	}
+	if got := namespaceDiff.TotalDelta.RequestCPU; got.Cmp(resource.MustParse("500m")) != 0 {
+		t.Errorf("default total delta = %s, want 500m", got.String())
+	}
+}
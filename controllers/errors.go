@@ -0,0 +1,30 @@
+package controllers
+
+import "fmt"
+
+// CollectError is a failure to collect one kind of objects in one namespace, Namespace is empty for cluster scoped
+// objects.
+type CollectError struct {
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind"`
+	Message   string `json:"message"`
+}
+
+func newCollectError(namespace, kind string, err error) CollectError {
+	return CollectError{Namespace: namespace, Kind: kind, Message: err.Error()}
+}
+
+func (err CollectError) Error() string {
+	if err.Namespace == "" {
+		return fmt.Sprintf("collect %s failed: %s", err.Kind, err.Message)
+	}
+	return fmt.Sprintf("collect %s of namespace %q failed: %s", err.Kind, err.Namespace, err.Message)
+}
+
+// Report is the content of the json result.
+type Report struct {
+	Responses []ControllerItem `json:"responses,omitempty"`
+	Errors    []CollectError   `json:"errors,omitempty"`
+	// Summary holds the groups of every --group-by
+	Summary []GroupItem `json:"summary,omitempty"`
+}
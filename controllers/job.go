@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// jobParallelism returns the number of pods a job runs concurrently.
+func jobParallelism(jobSpec batchv1.JobSpec) int32 {
+	if jobSpec.Suspend != nil && *jobSpec.Suspend {
+		return 0
+	}
+	var parallelism int32 = 1
+	if jobSpec.Parallelism != nil {
+		parallelism = *jobSpec.Parallelism
+	}
+	if jobSpec.Completions != nil && *jobSpec.Completions < parallelism {
+		parallelism = *jobSpec.Completions
+	}
+	return parallelism
+}
+
+// jobFinished returns whether job has completed or failed, its pods are not running anymore.
+func jobFinished(job batchv1.Job) bool {
+	if job.Status.CompletionTime != nil {
+		return true
+	} else if job.Spec.Completions != nil && job.Status.Succeeded >= *job.Spec.Completions {
+		return true
+	}
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// jobReplicas returns the number of pods job runs concurrently, at most its remaining completions, 0 once it has
+// finished.
+func jobReplicas(job batchv1.Job) int32 {
+	if jobFinished(job) {
+		return 0
+	}
+	parallelism := jobParallelism(job.Spec)
+	if job.Spec.Completions != nil && *job.Spec.Completions-job.Status.Succeeded < parallelism {
+		parallelism = *job.Spec.Completions - job.Status.Succeeded
+	}
+	return parallelism
+}
+
+func getJobItems(ctx context.Context, clientset kubernetes.Interface, namespace string, info *clusterInfo, options Options) ([]ControllerItem, error) {
+	var result []ControllerItem
+	err := listPages(ctx, "job", namespace, options, clientset.BatchV1().Jobs(namespace).List, func(controllers *batchv1.JobList) error {
+		for _, controller := range controllers.Items {
+			// jobs created by a cronjob are counted by the cronjob
+			if owner := metav1.GetControllerOf(&controller); owner != nil && owner.Kind == "CronJob" {
+				continue
+			}
+			controllerItem := ControllerItem{
+				Namespace:      controller.Namespace,
+				ControllerType: TypeJob,
+				Controller:     controller.Name,
+				Replicas:       jobReplicas(controller),
+			}
+			info.generatePodTemplateItem(&controllerItem, controller.Spec.Template)
+			result = append(result, controllerItem)
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const mi = 1024 * 1024
+
+// LegacyContainerItem is a container of the reports written before the exact quantities were stored, the cpu is in
+// millicores and the memory and ephemeral storage in MiB.
+type LegacyContainerItem struct {
+	Name                    string `json:"name,omitempty"`
+	RequestCPU              int64  `json:"requestCpu"`
+	RequestMem              int64  `json:"requestMem"`
+	RequestEphemeralStorate int64  `json:"requestEphemeralStorate,omitempty"`
+	LimitCPU                int64  `json:"limitCpu"`
+	LimitMem                int64  `json:"limitMem"`
+	LimitEphemeralStorate   int64  `json:"limitEphemeralStorate,omitempty"`
+}
+
+// LegacyControllerItem is a controller of the reports written before the exact quantities were stored, emptyDir and
+// storage are in MiB.
+type LegacyControllerItem struct {
+	Namespace      string                `json:"namespace,omitempty"`
+	ControllerType string                `json:"controllerType,omitempty"`
+	Controller     string                `json:"controller,omitempty"`
+	Replicas       int32                 `json:"replicas,omitempty"`
+	InitContainer  []LegacyContainerItem `json:"initContainer,omitempty"`
+	Container      []LegacyContainerItem `json:"container,omitempty"`
+	EmptyDir       int64                 `json:"emptyDir,omitempty"`
+	Storage        int64                 `json:"storage,omitempty"`
+	StorageNoSize  bool                  `json:"storageNoSize,omitempty"`
+}
+
+func legacyResourceList(cpu, memory, ephemeralStorage int64) v1.ResourceList {
+	list := v1.ResourceList{}
+	if cpu != 0 {
+		list[v1.ResourceCPU] = *resource.NewMilliQuantity(cpu, resource.DecimalSI)
+	}
+	if memory != 0 {
+		list[v1.ResourceMemory] = *resource.NewQuantity(memory*mi, resource.BinarySI)
+	}
+	if ephemeralStorage != 0 {
+		list[v1.ResourceEphemeralStorage] = *resource.NewQuantity(ephemeralStorage*mi, resource.BinarySI)
+	}
+	return list
+}
+
+func (container LegacyContainerItem) container() v1.Container {
+	return v1.Container{Name: container.Name, Resources: v1.ResourceRequirements{
+		Requests: legacyResourceList(container.RequestCPU, container.RequestMem, container.RequestEphemeralStorate),
+		Limits:   legacyResourceList(container.LimitCPU, container.LimitMem, container.LimitEphemeralStorate),
+	}}
+}
+
+// ControllerItem converts item to the exact quantities. The effective pod is computed from its containers, the
+// overhead and the sidecar init containers were not recorded.
+func (item LegacyControllerItem) ControllerItem() ControllerItem {
+	var podSpec v1.PodSpec
+	for _, container := range item.InitContainer {
+		podSpec.InitContainers = append(podSpec.InitContainers, container.container())
+	}
+	for _, container := range item.Container {
+		podSpec.Containers = append(podSpec.Containers, container.container())
+	}
+	effectivePod, total := (&clusterInfo{}).generatePodResource(podSpec, item.Replicas)
+	return ControllerItem{
+		Namespace:      item.Namespace,
+		ControllerType: item.ControllerType,
+		Controller:     item.Controller,
+		Replicas:       item.Replicas,
+		InitContainer:  generateContainers(podSpec.InitContainers),
+		Container:      generateContainers(podSpec.Containers),
+		EmptyDir:       *resource.NewQuantity(item.EmptyDir*mi, resource.BinarySI),
+		Storage:        *resource.NewQuantity(item.Storage*mi, resource.BinarySI),
+		StorageNoSize:  item.StorageNoSize,
+		EffectivePod:   effectivePod,
+		Total:          total,
+	}
+}
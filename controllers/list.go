@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// listPages lists the objects page by page with Limit/Continue and calls handle for every page, so large clusters
+// are never held in memory at once. The pages are logged as json in debug mode.
+func listPages[T metav1.ListInterface](ctx context.Context, kind, namespace string, options Options,
+	list func(context.Context, metav1.ListOptions) (T, error), handle func(T) error) error {
+	listOptions := metav1.ListOptions{Limit: options.PageSize}
+	for {
+		page, err := list(ctx, listOptions)
+		if err != nil {
+			return err
+		}
+		if options.DebugInfo {
+			if pageJson, err := json.Marshal(page); err != nil {
+				return err
+			} else {
+				klog.Infof("namespaces %s %s:\n%s", namespace, kind, pageJson)
+			}
+		}
+		if err := handle(page); err != nil {
+			return err
+		}
+		if listOptions.Continue = page.GetContinue(); listOptions.Continue == "" {
+			return nil
+		}
+	}
+}
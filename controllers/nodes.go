@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+)
+
+// NodeItem is the capacity and allocatable of a node, and the sums of the requests and limits of the non terminated
+// pods scheduled on it, the number of pods is requested as the pods resource.
+type NodeItem struct {
+	Name          string            `json:"name"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Unschedulable bool              `json:"unschedulable,omitempty"`
+	Capacity      v1.ResourceList   `json:"capacity"`
+	Allocatable   v1.ResourceList   `json:"allocatable"`
+	Requests      v1.ResourceList   `json:"requests"`
+	Limits        v1.ResourceList   `json:"limits"`
+	// RequestRatios divide the requests by the allocatable, LimitRatios the limits, a limit ratio above 1 is
+	// overcommitted
+	RequestRatios map[v1.ResourceName]float64 `json:"requestRatios,omitempty"`
+	LimitRatios   map[v1.ResourceName]float64 `json:"limitRatios,omitempty"`
+}
+
+// NodeReport holds the nodes, their sum, and the requests of the pending pods not scheduled on any node.
+type NodeReport struct {
+	Nodes []NodeItem `json:"nodes"`
+	Total NodeItem   `json:"total"`
+	// UnscheduledRequests sums the requests of the pods without node, including their number as the pods resource
+	UnscheduledRequests v1.ResourceList `json:"unscheduledRequests,omitempty"`
+}
+
+func newNodeItem(name string) NodeItem {
+	return NodeItem{Name: name, Capacity: v1.ResourceList{}, Allocatable: v1.ResourceList{}, Requests: v1.ResourceList{}, Limits: v1.ResourceList{}}
+}
+
+func resourceRatios(list, allocatable v1.ResourceList) map[v1.ResourceName]float64 {
+	ratios := map[v1.ResourceName]float64{}
+	for name, quantity := range allocatable {
+		if quantity.IsZero() {
+			continue
+		}
+		value := list[name]
+		ratios[name] = value.AsApproximateFloat64() / quantity.AsApproximateFloat64()
+	}
+	return ratios
+}
+
+func (item *NodeItem) setRatios() {
+	item.RequestRatios = resourceRatios(item.Requests, item.Allocatable)
+	item.LimitRatios = resourceRatios(item.Limits, item.Allocatable)
+}
+
+func addPod(requests, limits v1.ResourceList, pod v1.Pod) {
+	addResourceList(requests, podRequests(pod.Spec, pod.Spec.Overhead))
+	addResourceList(requests, v1.ResourceList{v1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI)})
+	addResourceList(limits, podLimits(pod.Spec, pod.Spec.Overhead))
+}
+
+// GetNodeReport returns the nodes matching selector sorted by name, selector is a label selector, empty selects all.
+func GetNodeReport(ctx context.Context, clientset kubernetes.Interface, selector string, options Options) (NodeReport, error) {
+	report := NodeReport{Total: newNodeItem("TOTAL"), UnscheduledRequests: v1.ResourceList{}}
+	nodeSelector, err := labels.Parse(selector)
+	if err != nil {
+		return report, err
+	}
+	nodes := map[string]*NodeItem{}
+	err = listPages(ctx, "node", "", options, clientset.CoreV1().Nodes().List, func(nodeList *v1.NodeList) error {
+		for _, node := range nodeList.Items {
+			if !nodeSelector.Matches(labels.Set(node.Labels)) {
+				continue
+			}
+			item := newNodeItem(node.Name)
+			item.Labels = node.Labels
+			item.Unschedulable = node.Spec.Unschedulable
+			addResourceList(item.Capacity, node.Status.Capacity)
+			addResourceList(item.Allocatable, node.Status.Allocatable)
+			nodes[node.Name] = &item
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	err = listPages(ctx, "pod", "", options, clientset.CoreV1().Pods("").List, func(pods *v1.PodList) error {
+		for _, pod := range pods.Items {
+			// finished pods do not consume any resources
+			if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+				continue
+			}
+			if pod.Spec.NodeName == "" {
+				addPod(report.UnscheduledRequests, v1.ResourceList{}, pod)
+			} else if node, ok := nodes[pod.Spec.NodeName]; ok {
+				addPod(node.Requests, node.Limits, pod)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	for _, node := range nodes {
+		node.setRatios()
+		addResourceList(report.Total.Capacity, node.Capacity)
+		addResourceList(report.Total.Allocatable, node.Allocatable)
+		addResourceList(report.Total.Requests, node.Requests)
+		addResourceList(report.Total.Limits, node.Limits)
+		report.Nodes = append(report.Nodes, *node)
+	}
+	report.Total.setRatios()
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].Name < report.Nodes[j].Name
+	})
+	return report, nil
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testNode(name string, labels map[string]string, cpu, memory string, gpus int64) *v1.Node {
+	allocatable := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse(memory),
+		v1.ResourcePods:   resource.MustParse("110"),
+	}
+	if gpus > 0 {
+		allocatable["nvidia.com/gpu"] = *resource.NewQuantity(gpus, resource.DecimalSI)
+	}
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status:     v1.NodeStatus{Capacity: allocatable, Allocatable: allocatable},
+	}
+}
+
+func testNodePod(name, nodeName string, phase v1.PodPhase, cpu, memory string) *v1.Pod {
+	spec := testPodSpec(cpu, memory)
+	spec.NodeName = nodeName
+	return &v1.Pod{ObjectMeta: objectMeta(name, nil), Spec: spec, Status: v1.PodStatus{Phase: phase}}
+}
+
+func TestGetNodeReport(t *testing.T) {
+	overcommitted := testNodePod("overcommitted", "gpu-1", v1.PodRunning, "500m", "1Gi")
+	overcommitted.Spec.Containers[0].Resources.Limits = v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), "nvidia.com/gpu": resource.MustParse("1")}
+	overcommitted.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+	clientset := fake.NewSimpleClientset(
+		testNode("worker-1", map[string]string{"pool": "general"}, "2", "4Gi", 0),
+		testNode("gpu-1", map[string]string{"pool": "gpu"}, "2", "8Gi", 2),
+		testNode("master", map[string]string{"pool": "control-plane"}, "2", "4Gi", 0),
+		testNodePod("web", "worker-1", v1.PodRunning, "500m", "1Gi"),
+		testNodePod("worker", "worker-1", v1.PodRunning, "1", "1Gi"),
+		overcommitted,
+		// finished pods are skipped, pending ones are unscheduled
+		testNodePod("job", "worker-1", v1.PodSucceeded, "1", "1Gi"),
+		testNodePod("pending", "", v1.PodPending, "3", "2Gi"),
+		testNodePod("etcd", "master", v1.PodRunning, "1", "1Gi"),
+	)
+
+	report, err := GetNodeReport(context.Background(), clientset, "pool in (general,gpu)", Options{PageSize: 1})
+	if err != nil {
+		t.Fatalf("GetNodeReport() error = %v", err)
+	}
+	if len(report.Nodes) != 2 || report.Nodes[0].Name != "gpu-1" || report.Nodes[1].Name != "worker-1" {
+		t.Fatalf("GetNodeReport() nodes = %+v, want gpu-1 and worker-1", report.Nodes)
+	}
+
+	worker := report.Nodes[1]
+	requestCPU, pods := worker.Requests[v1.ResourceCPU], worker.Requests[v1.ResourcePods]
+	if requestCPU.Cmp(resource.MustParse("1500m")) != 0 || pods.Value() != 2 {
+		t.Errorf("worker-1 requests = %v, want 1500m of 2 pods", worker.Requests)
+	}
+	if worker.RequestRatios[v1.ResourceCPU] != 0.75 || worker.RequestRatios[v1.ResourceMemory] != 0.5 {
+		t.Errorf("worker-1 request ratios = %v, want cpu 0.75, memory 0.5", worker.RequestRatios)
+	}
+
+	gpu := report.Nodes[0]
+	if gpu.LimitRatios[v1.ResourceCPU] != 2 || gpu.RequestRatios["nvidia.com/gpu"] != 0.5 {
+		t.Errorf("gpu-1 ratios = %v, %v, want cpu limit 2, gpu request 0.5", gpu.LimitRatios, gpu.RequestRatios)
+	}
+
+	totalCPU, totalGPU := report.Total.Allocatable[v1.ResourceCPU], report.Total.Allocatable["nvidia.com/gpu"]
+	if report.Total.Name != "TOTAL" || totalCPU.Cmp(resource.MustParse("4")) != 0 || totalGPU.Value() != 2 {
+		t.Errorf("total allocatable = %v, want 4 cpu and 2 gpus", report.Total.Allocatable)
+	}
+	if report.Total.RequestRatios[v1.ResourceCPU] != 0.5 {
+		t.Errorf("total cpu request ratio = %v, want 0.5", report.Total.RequestRatios[v1.ResourceCPU])
+	}
+
+	unscheduledCPU, unscheduledPods := report.UnscheduledRequests[v1.ResourceCPU], report.UnscheduledRequests[v1.ResourcePods]
+	if unscheduledCPU.Cmp(resource.MustParse("3")) != 0 || unscheduledPods.Value() != 1 {
+		t.Errorf("unscheduled requests = %v, want 3 cpu of 1 pod", report.UnscheduledRequests)
+	}
+
+	if _, err := GetNodeReport(context.Background(), clientset, "pool in (", Options{}); err == nil {
+		t.Error("GetNodeReport() with an invalid selector returns no error")
+	}
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"strings"
+)
+
+func controllerKey(kind, namespace, name string) string {
+	return strings.ToLower(kind) + "/" + namespace + "/" + name
+}
+
+// ownerResolver walks the controller ownerReferences of replicasets and jobs up to an accounted controller.
+type ownerResolver struct {
+	// accounted holds the keys of the controllers already in the result
+	accounted map[string]bool
+	// owners holds the controller ownerReference of every replicaset and job, nil when the object has none
+	owners map[string]*metav1.OwnerReference
+}
+
+// accountedKeysByNamespace returns the controllerKey of items by namespace, every key is under NamespaceAll too.
+func accountedKeysByNamespace(items []ControllerItem) map[string][]string {
+	result := map[string][]string{}
+	for _, item := range items {
+		key := controllerKey(item.ControllerType, item.Namespace, item.Controller)
+		result[item.Namespace] = append(result[item.Namespace], key)
+		if item.Namespace != metav1.NamespaceAll {
+			result[metav1.NamespaceAll] = append(result[metav1.NamespaceAll], key)
+		}
+	}
+	return result
+}
+
+func newOwnerResolver(accountedKeys []string) *ownerResolver {
+	resolver := &ownerResolver{accounted: make(map[string]bool, len(accountedKeys)), owners: map[string]*metav1.OwnerReference{}}
+	for _, key := range accountedKeys {
+		resolver.accounted[key] = true
+	}
+	return resolver
+}
+
+func (resolver *ownerResolver) addOwner(kind string, object metav1.Object) {
+	resolver.owners[controllerKey(kind, object.GetNamespace(), object.GetName())] = metav1.GetControllerOf(object)
+}
+
+// accountedKey returns the key of owner, or of the first controller up its ownerReferences chain, which is already
+// accounted for, empty if there is none. Owners are always in the namespace of the object they own.
+func (resolver *ownerResolver) accountedKey(namespace string, owner *metav1.OwnerReference) string {
+	// the depth limit guards against ownerReference cycles
+	for depth := 0; owner != nil && depth < 10; depth++ {
+		key := controllerKey(owner.Kind, namespace, owner.Name)
+		if resolver.accounted[key] {
+			return key
+		}
+		next, ok := resolver.owners[key]
+		if !ok {
+			return ""
+		}
+		owner = next
+	}
+	return ""
+}
+
+// isAccounted reports whether owner, or any controller up its ownerReferences chain, is already accounted for.
+func (resolver *ownerResolver) isAccounted(namespace string, owner *metav1.OwnerReference) bool {
+	return resolver.accountedKey(namespace, owner) != ""
+}
+
+// getOrphanItems returns the replicasets and running pods which are not owned by any controller of accountedKeys, the
+// controllerKey of the controllers of namespace, and the controllerKey of every running pod by its namespace/name, an
+// orphan pod is its own controller.
+func getOrphanItems(ctx context.Context, clientset kubernetes.Interface, namespace string, info *clusterInfo, accountedKeys []string, options Options) ([]ControllerItem, map[string]string, error) {
+	resolver := newOwnerResolver(accountedKeys)
+	var result []ControllerItem
+	podControllers := map[string]string{}
+	err := listPages(ctx, "replicaset", namespace, options, clientset.AppsV1().ReplicaSets(namespace).List, func(controllers *appsv1.ReplicaSetList) error {
+		for _, controller := range controllers.Items {
+			resolver.addOwner("ReplicaSet", &controller)
+			if resolver.isAccounted(controller.Namespace, metav1.GetControllerOf(&controller)) {
+				continue
+			}
+			controllerItem := ControllerItem{
+				Namespace:      controller.Namespace,
+				ControllerType: TypeReplicaSet,
+				Controller:     controller.Name,
+				Replicas:       specReplicas(controller.Spec.Replicas),
+			}
+			info.generatePodTemplateItem(&controllerItem, controller.Spec.Template)
+			result = append(result, controllerItem)
+			resolver.accounted[controllerKey(controllerItem.ControllerType, controllerItem.Namespace, controllerItem.Controller)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = listPages(ctx, "job", namespace, Options{PageSize: options.PageSize}, clientset.BatchV1().Jobs(namespace).List, func(jobs *batchv1.JobList) error {
+		for i := range jobs.Items {
+			resolver.addOwner("Job", &jobs.Items[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = listPages(ctx, "pod", namespace, options, clientset.CoreV1().Pods(namespace).List, func(pods *v1.PodList) error {
+		for _, pod := range pods.Items {
+			// finished pods do not consume any resources
+			if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+				continue
+			}
+			if key := resolver.accountedKey(pod.Namespace, metav1.GetControllerOf(&pod)); key != "" {
+				podControllers[pod.Namespace+"/"+pod.Name] = key
+				continue
+			}
+			controllerItem := ControllerItem{
+				Namespace:      pod.Namespace,
+				ControllerType: TypePod,
+				Controller:     pod.Name,
+				Replicas:       1,
+			}
+			info.generatePodTemplateItem(&controllerItem, v1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec})
+			result = append(result, controllerItem)
+			podControllers[pod.Namespace+"/"+pod.Name] = controllerKey(controllerItem.ControllerType, pod.Namespace, pod.Name)
+		}
+		return nil
+	})
+	return result, podControllers, err
+}
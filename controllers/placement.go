@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	v1 "k8s.io/api/core/v1"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+)
+
+// PlacementItem is the node selector, the required node affinity and the tolerations of a pod template, which select
+// the nodes a DaemonSet runs on.
+type PlacementItem struct {
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	NodeAffinity *v1.NodeSelector  `json:"nodeAffinity,omitempty"`
+	Tolerations  []v1.Toleration   `json:"tolerations,omitempty"`
+}
+
+// daemonSetTolerations are added to the pods of every DaemonSet by the DaemonSet controller.
+var daemonSetTolerations = []v1.Toleration{
+	{Key: v1.TaintNodeNotReady, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+	{Key: v1.TaintNodeUnreachable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+	{Key: v1.TaintNodeDiskPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+	{Key: v1.TaintNodeMemoryPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+	{Key: v1.TaintNodePIDPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+	{Key: v1.TaintNodeUnschedulable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+}
+
+func newPlacementItem(podSpec v1.PodSpec) *PlacementItem {
+	placement := &PlacementItem{NodeSelector: podSpec.NodeSelector, Tolerations: podSpec.Tolerations}
+	if podSpec.Affinity != nil && podSpec.Affinity.NodeAffinity != nil {
+		placement.NodeAffinity = podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	}
+	return placement
+}
+
+// Matches returns whether a pod of placement runs on node: its node selector and required node affinity match the
+// labels of node, and the NoSchedule and NoExecute taints of node are tolerated. A nil placement matches every node.
+func (placement *PlacementItem) Matches(node *v1.Node) bool {
+	if placement == nil {
+		return true
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{NodeSelector: placement.NodeSelector}}
+	if placement.NodeAffinity != nil {
+		pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: placement.NodeAffinity}}
+	}
+	if match, err := nodeaffinity.GetRequiredNodeAffinity(pod).Match(node); err != nil || !match {
+		return false
+	}
+	tolerations := append(append([]v1.Toleration{}, placement.Tolerations...), daemonSetTolerations...)
+	_, untolerated := corev1helpers.FindMatchingUntoleratedTaint(node.Spec.Taints, tolerations, func(taint *v1.Taint) bool {
+		return taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute
+	})
+	return !untolerated
+}
+
+// countNodes returns the number of nodes placement matches.
+func (placement *PlacementItem) countNodes(nodes []v1.Node) int32 {
+	var count int32
+	for index := range nodes {
+		if placement.Matches(&nodes[index]) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testDaemonSet(name string, placement func(spec *v1.PodSpec)) *appsv1.DaemonSet {
+	template := testTemplate("100m", "64Mi")
+	placement(&template.Spec)
+	return &appsv1.DaemonSet{ObjectMeta: objectMeta(name, nil), Spec: appsv1.DaemonSetSpec{Template: template}}
+}
+
+func TestGetControllerItemsDaemonSetNodes(t *testing.T) {
+	worker := testNode("worker", map[string]string{"zone": "a"}, "4", "8Gi", 0)
+	// the DaemonSets tolerate cordoned nodes
+	cordoned := testNode("cordoned", map[string]string{"zone": "b"}, "4", "8Gi", 0)
+	cordoned.Spec.Taints = []v1.Taint{{Key: v1.TaintNodeUnschedulable, Effect: v1.TaintEffectNoSchedule}}
+	master := testNode("master", map[string]string{"zone": "a"}, "4", "8Gi", 0)
+	master.Spec.Taints = []v1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: v1.TaintEffectNoSchedule}}
+	daemonSets := []runtime.Object{
+		testDaemonSet("agent", func(spec *v1.PodSpec) {}),
+		testDaemonSet("logger", func(spec *v1.PodSpec) {
+			spec.Tolerations = []v1.Toleration{{Operator: v1.TolerationOpExists}}
+		}),
+		testDaemonSet("zonal", func(spec *v1.PodSpec) {
+			spec.Tolerations = []v1.Toleration{{Operator: v1.TolerationOpExists}}
+			spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}}}},
+			}}}
+		}),
+		testDaemonSet("nowhere", func(spec *v1.PodSpec) {
+			spec.NodeSelector = map[string]string{"zone": "c"}
+		}),
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		nodes    []v1.Node
+		replicas map[string]int32
+	}{
+		{"nodes", append([]runtime.Object{worker, cordoned, master}, daemonSets...), nil, map[string]int32{"agent": 2, "logger": 3, "zonal": 2, "nowhere": 0}},
+		// the given nodes are counted instead of the listed ones
+		{"given nodes", append([]runtime.Object{worker}, daemonSets...), []v1.Node{*worker, *cordoned, *master}, map[string]int32{"agent": 2, "logger": 3, "zonal": 2, "nowhere": 0}},
+		// a single node is assumed without nodes
+		{"no nodes", daemonSets, nil, map[string]int32{"agent": 1, "logger": 1, "zonal": 1, "nowhere": 1}},
+	}
+	for _, test := range tests {
+		clientset := fake.NewSimpleClientset(test.objects...)
+		items, collectErrors, err := GetControllerItems(context.Background(), clientset, nil, Options{CountDaemonSetNodes: true, Nodes: test.nodes})
+		if err != nil || len(collectErrors) > 0 {
+			t.Fatalf("%s: GetControllerItems() = %v, %v", test.name, collectErrors, err)
+		}
+		for name, replicas := range test.replicas {
+			item := findControllerItem(items, "Daemonset", name)
+			if item == nil || item.Replicas != replicas {
+				t.Errorf("%s: Daemonset %q = %+v, want %d replicas", test.name, name, item, replicas)
+			} else if item.Placement == nil {
+				t.Errorf("%s: Daemonset %q has no placement", test.name, name)
+			}
+		}
+	}
+}
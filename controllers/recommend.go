@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math"
+	"strings"
+)
+
+const (
+	PercentileP50 = "p50"
+	PercentileP95 = "p95"
+	PercentileMax = "max"
+)
+
+// QuantileItem holds the quantiles of a usage over a window.
+type QuantileItem struct {
+	P50 resource.Quantity `json:"p50"`
+	P95 resource.Quantity `json:"p95"`
+	Max resource.Quantity `json:"max"`
+}
+
+// UsageHistoryItem is the usage of a container over Window, the quantiles of the pods are combined by their maximum,
+// so that a controller is sized for its busiest pod.
+type UsageHistoryItem struct {
+	Window string       `json:"window"`
+	CPU    QuantileItem `json:"cpu"`
+	Memory QuantileItem `json:"memory"`
+}
+
+// ResourcePolicy recommends a request or limit from a percentile of the usage history plus a headroom ratio, e.g. p95
+// with headroom 0.2 is 120% of p95. The request or limit is not set when Percentile is empty.
+type ResourcePolicy struct {
+	Percentile string  `json:"percentile,omitempty" mapstructure:"percentile"`
+	Headroom   float64 `json:"headroom,omitempty" mapstructure:"headroom"`
+}
+
+// RecommendPolicy is a headroom policy of the controllers selected by Namespaces and ControllerTypes, empty selects all.
+type RecommendPolicy struct {
+	Name            string         `json:"name" mapstructure:"name"`
+	Namespaces      []string       `json:"namespaces,omitempty" mapstructure:"namespaces"`
+	ControllerTypes []string       `json:"controllerTypes,omitempty" mapstructure:"controllerTypes"`
+	CPURequest      ResourcePolicy `json:"cpuRequest" mapstructure:"cpuRequest"`
+	CPULimit        ResourcePolicy `json:"cpuLimit" mapstructure:"cpuLimit"`
+	MemoryRequest   ResourcePolicy `json:"memoryRequest" mapstructure:"memoryRequest"`
+	MemoryLimit     ResourcePolicy `json:"memoryLimit" mapstructure:"memoryLimit"`
+	// MinCPU and MinMemory are the lower bounds of the recommended requests, e.g. 10m and 16Mi
+	MinCPU    string `json:"minCpu,omitempty" mapstructure:"minCpu"`
+	MinMemory string `json:"minMemory,omitempty" mapstructure:"minMemory"`
+}
+
+// DefaultRecommendPolicy is used for the controllers selected by no configured policy: no cpu limit to avoid
+// throttling, and a memory limit above the peak.
+var DefaultRecommendPolicy = RecommendPolicy{
+	Name:          "default",
+	CPURequest:    ResourcePolicy{Percentile: PercentileP95, Headroom: 0.1},
+	MemoryRequest: ResourcePolicy{Percentile: PercentileP95, Headroom: 0.2},
+	MemoryLimit:   ResourcePolicy{Percentile: PercentileMax, Headroom: 0.2},
+	MinCPU:        "10m",
+	MinMemory:     "16Mi",
+}
+
+// Recommendation proposes the requests and limits of a container, the ephemeral storage is kept.
+type Recommendation struct {
+	Namespace      string           `json:"namespace"`
+	ControllerType string           `json:"controllerType"`
+	Controller     string           `json:"controller"`
+	ContainerType  string           `json:"containerType"`
+	Container      string           `json:"container"`
+	Policy         string           `json:"policy"`
+	Current        ResourceItem     `json:"current"`
+	Recommended    ResourceItem     `json:"recommended"`
+	History        UsageHistoryItem `json:"history"`
+}
+
+// ValidateRecommendPolicy checks the percentiles, headrooms and lower bounds of policy.
+func ValidateRecommendPolicy(policy RecommendPolicy) error {
+	if policy.Name == "" {
+		return fmt.Errorf("recommend policy has no name")
+	}
+	for name, resourcePolicy := range map[string]ResourcePolicy{
+		"cpuRequest": policy.CPURequest, "cpuLimit": policy.CPULimit, "memoryRequest": policy.MemoryRequest, "memoryLimit": policy.MemoryLimit,
+	} {
+		if resourcePolicy.Percentile != "" && resourcePolicy.Percentile != PercentileP50 &&
+			resourcePolicy.Percentile != PercentileP95 && resourcePolicy.Percentile != PercentileMax {
+			return fmt.Errorf("unknown percentile %q of %s of recommend policy %q, it must be %s, %s or %s",
+				resourcePolicy.Percentile, name, policy.Name, PercentileP50, PercentileP95, PercentileMax)
+		} else if resourcePolicy.Headroom < 0 {
+			return fmt.Errorf("negative headroom %v of %s of recommend policy %q", resourcePolicy.Headroom, name, policy.Name)
+		}
+	}
+	for _, quantity := range []string{policy.MinCPU, policy.MinMemory} {
+		if quantity == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(quantity); err != nil {
+			return fmt.Errorf("invalid lower bound %q of recommend policy %q: %v", quantity, policy.Name, err)
+		}
+	}
+	return nil
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectPolicy returns the first policy selecting controllerItem, DefaultRecommendPolicy if there is none.
+func selectPolicy(policies []RecommendPolicy, controllerItem ControllerItem) RecommendPolicy {
+	for _, policy := range policies {
+		if matchesAny(policy.Namespaces, controllerItem.Namespace) && matchesAny(policy.ControllerTypes, controllerItem.ControllerType) {
+			return policy
+		}
+	}
+	return DefaultRecommendPolicy
+}
+
+func (quantiles QuantileItem) percentile(percentile string) resource.Quantity {
+	if percentile == PercentileP50 {
+		return quantiles.P50
+	} else if percentile == PercentileP95 {
+		return quantiles.P95
+	}
+	return quantiles.Max
+}
+
+// ceil rounds value up, ignoring the floating point errors of the headroom, e.g. 200 * 1.1 is 220.00000000000003.
+func ceil(value float64) int64 {
+	return int64(math.Ceil(math.Round(value*1e6) / 1e6))
+}
+
+// recommendCPU returns the percentile of quantiles plus headroom rounded up to a millicore, zero if there is no
+// percentile.
+func recommendCPU(quantiles QuantileItem, policy ResourcePolicy) resource.Quantity {
+	if policy.Percentile == "" {
+		return resource.Quantity{}
+	}
+	usage := quantiles.percentile(policy.Percentile)
+	return *resource.NewMilliQuantity(ceil(float64(usage.MilliValue())*(1+policy.Headroom)), resource.DecimalSI)
+}
+
+// recommendMemory returns the percentile of quantiles plus headroom rounded up to a Mi, zero if there is no percentile.
+func recommendMemory(quantiles QuantileItem, policy ResourcePolicy) resource.Quantity {
+	if policy.Percentile == "" {
+		return resource.Quantity{}
+	}
+	usage := quantiles.percentile(policy.Percentile)
+	return *resource.NewQuantity(ceil(float64(usage.Value())*(1+policy.Headroom)/(1<<20))<<20, resource.BinarySI)
+}
+
+// atLeast returns quantity raised to minimum, minimum is ignored when it is empty.
+func atLeast(quantity resource.Quantity, minimum string) resource.Quantity {
+	if minimum == "" {
+		return quantity
+	}
+	if minQuantity := resource.MustParse(minimum); quantity.Cmp(minQuantity) < 0 {
+		return minQuantity
+	}
+	return quantity
+}
+
+func maxQuantity(quantity, newQuantity resource.Quantity) resource.Quantity {
+	if newQuantity.Cmp(quantity) > 0 {
+		return newQuantity
+	}
+	return quantity
+}
+
+func recommendContainer(container ContainerItem, policy RecommendPolicy) ResourceItem {
+	history := container.History
+	recommended := ResourceItem{
+		RequestCPU:              atLeast(recommendCPU(history.CPU, policy.CPURequest), policy.MinCPU),
+		RequestMem:              atLeast(recommendMemory(history.Memory, policy.MemoryRequest), policy.MinMemory),
+		RequestEphemeralStorate: container.RequestEphemeralStorate,
+		LimitEphemeralStorate:   container.LimitEphemeralStorate,
+	}
+	// the limits are never below the requests
+	if policy.CPULimit.Percentile != "" {
+		recommended.LimitCPU = maxQuantity(recommendCPU(history.CPU, policy.CPULimit), recommended.RequestCPU)
+	}
+	if policy.MemoryLimit.Percentile != "" {
+		recommended.LimitMem = maxQuantity(recommendMemory(history.Memory, policy.MemoryLimit), recommended.RequestMem)
+	}
+	return recommended
+}
+
+// Recommend proposes the requests and limits of every container with a usage history by the first policy which
+// selects its controller, the policies are checked by ValidateRecommendPolicy.
+func Recommend(items []ControllerItem, policies []RecommendPolicy) []Recommendation {
+	var result []Recommendation
+	for _, controllerItem := range items {
+		policy := selectPolicy(policies, controllerItem)
+		add := func(containerType string, containers []ContainerItem) {
+			for _, container := range containers {
+				if container.History == nil {
+					continue
+				}
+				result = append(result, Recommendation{
+					Namespace:      controllerItem.Namespace,
+					ControllerType: controllerItem.ControllerType,
+					Controller:     controllerItem.Controller,
+					ContainerType:  containerType,
+					Container:      container.Name,
+					Policy:         policy.Name,
+					Current:        container.ResourceItem,
+					Recommended:    recommendContainer(container, policy),
+					History:        *container.History,
+				})
+			}
+		}
+		add("initContainer", controllerItem.InitContainer)
+		add("container", controllerItem.Container)
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func testHistory(cpu, memory [3]string) *UsageHistoryItem {
+	return &UsageHistoryItem{
+		Window: "7d",
+		CPU:    QuantileItem{P50: resource.MustParse(cpu[0]), P95: resource.MustParse(cpu[1]), Max: resource.MustParse(cpu[2])},
+		Memory: QuantileItem{P50: resource.MustParse(memory[0]), P95: resource.MustParse(memory[1]), Max: resource.MustParse(memory[2])},
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	web := testDiffItem("shop", "Deployment", "web", 2, map[string]string{"app": "1"})
+	web.Container[0].History = testHistory([3]string{"100m", "200m", "500m"}, [3]string{"100Mi", "200Mi", "300Mi"})
+	idle := testDiffItem("shop", "Deployment", "idle", 1, map[string]string{"app": "1", "sidecar": "1"})
+	for i := range idle.Container {
+		if idle.Container[i].Name == "app" {
+			idle.Container[i].History = testHistory([3]string{"0", "1m", "2m"}, [3]string{"1Mi", "1Mi", "1Mi"})
+		}
+	}
+	batch := testDiffItem("batch", "Job", "migrate", 1, map[string]string{"migrate": "1"})
+	batch.Container[0].History = testHistory([3]string{"1", "2", "3"}, [3]string{"1Gi", "1Gi", "2Gi"})
+
+	policies := []RecommendPolicy{{
+		Name:            "batch",
+		Namespaces:      []string{"batch"},
+		ControllerTypes: []string{"job", "CronJob"},
+		CPURequest:      ResourcePolicy{Percentile: PercentileP50},
+		CPULimit:        ResourcePolicy{Percentile: PercentileMax, Headroom: 0.5},
+		MemoryRequest:   ResourcePolicy{Percentile: PercentileMax},
+		MemoryLimit:     ResourcePolicy{Percentile: PercentileMax},
+	}}
+	for _, policy := range append(policies, DefaultRecommendPolicy) {
+		if err := ValidateRecommendPolicy(policy); err != nil {
+			t.Fatalf("ValidateRecommendPolicy(%s) error = %v", policy.Name, err)
+		}
+	}
+	result := Recommend([]ControllerItem{web, idle, batch}, policies)
+	want := []struct {
+		controller string
+		policy     string
+		// requestCpu, limitCpu, requestMem, limitMem
+		recommended [4]string
+	}{
+		// 200m * 1.1, 200Mi * 1.2 rounded up to Mi, 300Mi * 1.2
+		{"web", "default", [4]string{"220m", "0", "240Mi", "360Mi"}},
+		// the requests are raised to the lower bounds and the limits to the requests, the sidecar has no history
+		{"idle", "default", [4]string{"10m", "0", "16Mi", "16Mi"}},
+		{"migrate", "batch", [4]string{"1", "4500m", "2Gi", "2Gi"}},
+	}
+	if len(result) != len(want) {
+		t.Fatalf("Recommend() returns %d recommendations, want %d: %+v", len(result), len(want), result)
+	}
+	for index, test := range want {
+		recommendation := result[index]
+		if recommendation.Controller != test.controller || recommendation.Policy != test.policy || recommendation.Container == "sidecar" {
+			t.Errorf("recommendations[%d] = %s %s with policy %s", index, recommendation.Controller, recommendation.Container, recommendation.Policy)
+		}
+		recommended := recommendation.Recommended
+		for field, quantity := range []resource.Quantity{recommended.RequestCPU, recommended.LimitCPU, recommended.RequestMem, recommended.LimitMem} {
+			if quantity.Cmp(resource.MustParse(test.recommended[field])) != 0 {
+				t.Errorf("recommendations[%d] field %d = %s, want %s", index, field, quantity.String(), test.recommended[field])
+			}
+		}
+	}
+	if result[0].Current.RequestCPU.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("current requestCpu = %s, want 1", result[0].Current.RequestCPU.String())
+	}
+
+	for _, policy := range []RecommendPolicy{
+		{},
+		{Name: "p99", CPURequest: ResourcePolicy{Percentile: "p99"}},
+		{Name: "negative", MemoryLimit: ResourcePolicy{Percentile: PercentileMax, Headroom: -1}},
+		{Name: "min", MinCPU: "ten"},
+	} {
+		if err := ValidateRecommendPolicy(policy); err == nil {
+			t.Errorf("ValidateRecommendPolicy(%+v) returns no error", policy)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// clusterInfo holds cluster scoped objects which are referenced by pod templates, and the settings to size them.
+type clusterInfo struct {
+	runtimeClassOverheads map[string]v1.ResourceList
+	defaultStorageClass   string
+	// claims holds the persistentvolumeclaims by namespace and name
+	claims            map[string]map[string]v1.PersistentVolumeClaim
+	csiSizeAttributes map[string][]string
+	// namespaceLabels holds the labels by namespace when Options.NamespaceLabels is set
+	namespaceLabels map[string]map[string]string
+	// nodes are listed to count the replicas of the DaemonSets when Options.CountDaemonSetNodes is set
+	nodes []v1.Node
+}
+
+// getClusterInfo returns the objects which could be listed, and an error for every kind which could not.
+func getClusterInfo(ctx context.Context, clientset kubernetes.Interface, namespaces []string, options Options) (*clusterInfo, []CollectError) {
+	var collectErrors []CollectError
+	info := &clusterInfo{
+		runtimeClassOverheads: map[string]v1.ResourceList{},
+		defaultStorageClass:   unknownStorageClass,
+		claims:                map[string]map[string]v1.PersistentVolumeClaim{},
+		csiSizeAttributes:     options.CSISizeAttributes,
+		namespaceLabels:       map[string]map[string]string{},
+	}
+	if runtimeClasses, err := clientset.NodeV1().RuntimeClasses().List(ctx, metav1.ListOptions{}); apierrors.IsForbidden(err) {
+		klog.Warningf("list the runtimeclasses is forbidden, the pods are reported without overhead: %v", err)
+	} else if err != nil {
+		collectErrors = append(collectErrors, newCollectError("", "RuntimeClass", err))
+	} else {
+		for _, runtimeClass := range runtimeClasses.Items {
+			if runtimeClass.Overhead != nil {
+				info.runtimeClassOverheads[runtimeClass.Name] = runtimeClass.Overhead.PodFixed
+			}
+		}
+	}
+	if defaultStorageClass, err := getDefaultStorageClass(ctx, clientset); apierrors.IsForbidden(err) {
+		klog.Warningf("list the storageclasses is forbidden, the claims without storage class are reported in %q: %v", unknownStorageClass, err)
+	} else if err != nil {
+		collectErrors = append(collectErrors, newCollectError("", "StorageClass", err))
+	} else {
+		info.defaultStorageClass = defaultStorageClass
+	}
+	if options.NamespaceLabels {
+		err := listPages(ctx, "namespace", "", options, clientset.CoreV1().Namespaces().List, func(namespaceList *v1.NamespaceList) error {
+			for _, namespace := range namespaceList.Items {
+				info.namespaceLabels[namespace.Name] = namespace.Labels
+			}
+			return nil
+		})
+		if err != nil {
+			collectErrors = append(collectErrors, newCollectError("", "Namespace", err))
+		}
+	}
+	if options.CountDaemonSetNodes && len(options.Nodes) > 0 {
+		info.nodes = options.Nodes
+	} else if options.CountDaemonSetNodes {
+		err := listPages(ctx, "node", "", options, clientset.CoreV1().Nodes().List, func(nodeList *v1.NodeList) error {
+			info.nodes = append(info.nodes, nodeList.Items...)
+			return nil
+		})
+		if err != nil {
+			collectErrors = append(collectErrors, newCollectError("", "Node", err))
+		} else if len(info.nodes) == 0 {
+			klog.Warningf("there is no node to count the replicas of the DaemonSets, every DaemonSet is reported with 1 replica")
+		}
+	}
+	for _, namespace := range namespaces {
+		if err := getClaims(ctx, clientset, namespace, options, info.claims); err != nil {
+			collectErrors = append(collectErrors, newCollectError(namespace, "PersistentVolumeClaim", err))
+		}
+	}
+	return info, collectErrors
+}
+
+// podOverhead returns the overhead the RuntimeClass admission controller will set on pods created from podSpec.
+func (info *clusterInfo) podOverhead(podSpec v1.PodSpec) v1.ResourceList {
+	if podSpec.Overhead != nil {
+		return podSpec.Overhead
+	}
+	if podSpec.RuntimeClassName != nil {
+		return info.runtimeClassOverheads[*podSpec.RuntimeClassName]
+	}
+	return nil
+}
+
+// containerRequests returns the requests of container, defaulted from its limits like the apiserver does for pods.
+func containerRequests(container v1.Container) v1.ResourceList {
+	requests := container.Resources.Requests.DeepCopy()
+	if requests == nil {
+		requests = v1.ResourceList{}
+	}
+	for name, quantity := range container.Resources.Limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = quantity.DeepCopy()
+		}
+	}
+	return requests
+}
+
+func addResourceList(list, newList v1.ResourceList) {
+	for name, quantity := range newList {
+		if value, ok := list[name]; !ok {
+			list[name] = quantity.DeepCopy()
+		} else {
+			value.Add(quantity)
+			list[name] = value
+		}
+	}
+}
+
+func maxResourceList(list, newList v1.ResourceList) {
+	for name, quantity := range newList {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func isRestartableInitContainer(container v1.Container) bool {
+	return container.RestartPolicy != nil && *container.RestartPolicy == v1.ContainerRestartPolicyAlways
+}
+
+// podResources sums app containers and restartable (sidecar) init containers, and takes the max against every
+// regular init container plus the sidecars started before it, the same as the scheduler does.
+func podResources(podSpec v1.PodSpec, resourcesFn func(v1.Container) v1.ResourceList) v1.ResourceList {
+	result := v1.ResourceList{}
+	for _, container := range podSpec.Containers {
+		addResourceList(result, resourcesFn(container))
+	}
+	restartableInitContainerResources := v1.ResourceList{}
+	initContainerResources := v1.ResourceList{}
+	for _, container := range podSpec.InitContainers {
+		containerResources := resourcesFn(container)
+		if isRestartableInitContainer(container) {
+			addResourceList(result, containerResources)
+			addResourceList(restartableInitContainerResources, containerResources)
+			containerResources = restartableInitContainerResources
+		} else {
+			tmp := v1.ResourceList{}
+			addResourceList(tmp, containerResources)
+			addResourceList(tmp, restartableInitContainerResources)
+			containerResources = tmp
+		}
+		maxResourceList(initContainerResources, containerResources)
+	}
+	maxResourceList(result, initContainerResources)
+	return result
+}
+
+func podRequests(podSpec v1.PodSpec, overhead v1.ResourceList) v1.ResourceList {
+	requests := podResources(podSpec, containerRequests)
+	addResourceList(requests, overhead)
+	return requests
+}
+
+func podLimits(podSpec v1.PodSpec, overhead v1.ResourceList) v1.ResourceList {
+	limits := podResources(podSpec, func(container v1.Container) v1.ResourceList {
+		return container.Resources.Limits
+	})
+	// overhead is only added to the limits which are set
+	for name, quantity := range overhead {
+		if value, ok := limits[name]; ok {
+			value.Add(quantity)
+			limits[name] = value
+		}
+	}
+	return limits
+}
+
+func generateResourceItem(requests, limits v1.ResourceList) ResourceItem {
+	return ResourceItem{
+		RequestCPU:              *requests.Cpu(),
+		RequestMem:              *requests.Memory(),
+		RequestEphemeralStorate: *requests.StorageEphemeral(),
+		LimitCPU:                *limits.Cpu(),
+		LimitMem:                *limits.Memory(),
+		LimitEphemeralStorate:   *limits.StorageEphemeral(),
+	}
+}
+
+func multiplyResourceList(list v1.ResourceList, replicas int32) v1.ResourceList {
+	result := v1.ResourceList{}
+	for name, quantity := range list {
+		value := quantity.DeepCopy()
+		value.Mul(int64(replicas))
+		result[name] = value
+	}
+	return result
+}
+
+// generatePodResource returns the effective pod resources and the resources of all replicas.
+func (info *clusterInfo) generatePodResource(podSpec v1.PodSpec, replicas int32) (ResourceItem, ResourceItem) {
+	overhead := info.podOverhead(podSpec)
+	requests, limits := podRequests(podSpec, overhead), podLimits(podSpec, overhead)
+	return generateResourceItem(requests, limits),
+		generateResourceItem(multiplyResourceList(requests, replicas), multiplyResourceList(limits, replicas))
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// defaultDeploymentMaxSurge is the maxSurge of a rolling update Deployment without one, e.g. in manifests.
+var defaultDeploymentMaxSurge = intstr.FromString("25%")
+
+// RolloutItem is the update strategy of a controller and the extra pods it runs during a rollout. PeakTotal is the
+// EffectivePod multiplied by Replicas plus Surge, the footprint at the peak of a rollout.
+type RolloutItem struct {
+	Strategy  string       `json:"strategy"`
+	Surge     int32        `json:"surge"`
+	PeakTotal ResourceItem `json:"peakTotal"`
+}
+
+// PeakReplicas returns the replicas of item at the peak of a rollout, its replicas without rollout.
+func (item ControllerItem) PeakReplicas() int32 {
+	if item.Rollout == nil {
+		return item.Replicas
+	}
+	return item.Replicas + item.Rollout.Surge
+}
+
+// PeakTotal returns the footprint of item at the peak of a rollout, its Total without rollout.
+func (item ControllerItem) PeakTotal() ResourceItem {
+	if item.Rollout == nil {
+		return item.Total
+	}
+	return item.Rollout.PeakTotal
+}
+
+// setRollout sets the rollout of item, it must be called after the EffectivePod is generated.
+func (item *ControllerItem) setRollout(strategy string, surge int32) {
+	item.Rollout = &RolloutItem{
+		Strategy:  strategy,
+		Surge:     surge,
+		PeakTotal: multiplyResourceItem(item.EffectivePod, item.Replicas+surge),
+	}
+}
+
+// scaledSurge returns maxSurge of replicas, a percentage is rounded up like the controllers do.
+func scaledSurge(maxSurge *intstr.IntOrString, replicas int32) int32 {
+	if maxSurge == nil {
+		return 0
+	}
+	surge, err := intstr.GetScaledValueFromIntOrPercent(maxSurge, int(replicas), true)
+	if err != nil || surge < 0 {
+		return 0
+	}
+	return int32(surge)
+}
+
+// setDeploymentRollout sets the rollout of a Deployment: a rolling update creates up to maxSurge new pods before the
+// old ones are terminated, a recreate terminates all old pods first.
+func (item *ControllerItem) setDeploymentRollout(strategy appsv1.DeploymentStrategy) {
+	if strategy.Type == appsv1.RecreateDeploymentStrategyType {
+		item.setRollout(string(strategy.Type), 0)
+		return
+	}
+	maxSurge := &defaultDeploymentMaxSurge
+	if strategy.RollingUpdate != nil && strategy.RollingUpdate.MaxSurge != nil {
+		maxSurge = strategy.RollingUpdate.MaxSurge
+	}
+	item.setRollout(string(appsv1.RollingUpdateDeploymentStrategyType), scaledSurge(maxSurge, item.Replicas))
+}
+
+// setStatefulSetRollout sets the rollout of a StatefulSet, which never surges: a rolling update terminates up to
+// maxUnavailable pods before it recreates them.
+func (item *ControllerItem) setStatefulSetRollout(strategy appsv1.StatefulSetUpdateStrategy) {
+	strategyType := strategy.Type
+	if strategyType == "" {
+		strategyType = appsv1.RollingUpdateStatefulSetStrategyType
+	}
+	item.setRollout(string(strategyType), 0)
+}
+
+// setDaemonSetRollout sets the rollout of a DaemonSet: a rolling update with maxSurge starts the new pod of a node
+// before the old one is terminated, maxSurge is 0 by default.
+func (item *ControllerItem) setDaemonSetRollout(strategy appsv1.DaemonSetUpdateStrategy) {
+	if strategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		item.setRollout(string(strategy.Type), 0)
+		return
+	}
+	var maxSurge *intstr.IntOrString
+	if strategy.RollingUpdate != nil {
+		maxSurge = strategy.RollingUpdate.MaxSurge
+	}
+	item.setRollout(string(appsv1.RollingUpdateDaemonSetStrategyType), scaledSurge(maxSurge, item.Replicas))
+}
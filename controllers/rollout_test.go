@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func intOrStringPtr(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
+
+func TestGetControllerItemsRollout(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		// 25% of 3 replicas is rounded up to 1
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(3), Template: testTemplate("100m", "128Mi"), Strategy: appsv1.DeploymentStrategy{
+			Type: appsv1.RollingUpdateDeploymentStrategyType, RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: intOrStringPtr(intstr.FromString("25%"))},
+		}}},
+		// maxSurge defaults to 25% without strategy, e.g. in manifests
+		&appsv1.Deployment{ObjectMeta: objectMeta("api", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(8), Template: testTemplate("100m", "128Mi")}},
+		&appsv1.Deployment{ObjectMeta: objectMeta("batch", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(4), Template: testTemplate("1", "1Gi"), Strategy: appsv1.DeploymentStrategy{
+			Type: appsv1.RecreateDeploymentStrategyType,
+		}}},
+		&appsv1.StatefulSet{ObjectMeta: objectMeta("db", nil), Spec: appsv1.StatefulSetSpec{Replicas: int32Ptr(3), Template: testTemplate("1", "1Gi")}},
+		&appsv1.DaemonSet{ObjectMeta: objectMeta("agent", nil), Spec: appsv1.DaemonSetSpec{Template: testTemplate("100m", "64Mi"), UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+			Type: appsv1.RollingUpdateDaemonSetStrategyType, RollingUpdate: &appsv1.RollingUpdateDaemonSet{MaxSurge: intOrStringPtr(intstr.FromInt32(1))},
+		}}, Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3}},
+	)
+	items, collectErrors, err := GetControllerItems(context.Background(), clientset, nil, Options{})
+	if err != nil || len(collectErrors) > 0 {
+		t.Fatalf("GetControllerItems() = %v, %v", collectErrors, err)
+	}
+
+	tests := []struct {
+		controllerType string
+		name           string
+		strategy       string
+		surge          int32
+	}{
+		{"Deployment", "web", "RollingUpdate", 1},
+		{"Deployment", "api", "RollingUpdate", 2},
+		{"Deployment", "batch", "Recreate", 0},
+		{"Statefulset", "db", "RollingUpdate", 0},
+		{"Daemonset", "agent", "RollingUpdate", 1},
+	}
+	for _, test := range tests {
+		item := findControllerItem(items, test.controllerType, test.name)
+		if item == nil || item.Rollout == nil || item.Rollout.Strategy != test.strategy || item.Rollout.Surge != test.surge || item.PeakReplicas() != item.Replicas+test.surge {
+			t.Errorf("%s %q = %+v, want %s with a surge of %d", test.controllerType, test.name, item, test.strategy, test.surge)
+		}
+	}
+	web := findControllerItem(items, "Deployment", "web")
+	if peakTotal := web.PeakTotal(); peakTotal.RequestCPU.Cmp(resource.MustParse("400m")) != 0 || peakTotal.LimitMem.Cmp(resource.MustParse("512Mi")) != 0 {
+		t.Errorf("web peak total = %+v, want 4 * 100m and 4 * 128Mi", peakTotal)
+	}
+
+	groups := AggregateControllerItems(items, GroupByNamespace)
+	if len(groups) != 1 {
+		t.Fatalf("AggregateControllerItems() = %+v", groups)
+	}
+	// 4 web, 10 api, 4 batch, 3 db and 4 agent replicas
+	group := groups[0]
+	if group.Replicas != 21 || group.PeakReplicas != 25 || group.PeakTotal.RequestCPU.Cmp(resource.MustParse("8800m")) != 0 {
+		t.Errorf("namespace group = %+v, want 25 peak replicas and 8800m peak cpu", group)
+	}
+}
+
+func TestControllerItemPeakWithoutRollout(t *testing.T) {
+	item := ControllerItem{Replicas: 2, Total: ResourceItem{RequestCPU: resource.MustParse("200m")}}
+	if peakTotal := item.PeakTotal(); item.PeakReplicas() != 2 || peakTotal.RequestCPU.Cmp(resource.MustParse("200m")) != 0 {
+		t.Errorf("PeakReplicas() = %d, PeakTotal() = %+v, want the replicas and total", item.PeakReplicas(), peakTotal)
+	}
+}
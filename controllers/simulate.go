@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sort"
+)
+
+const (
+	// DefaultNodePods is the pods of a node pool without pods, the default max pods of the kubelet
+	DefaultNodePods = 110
+	// nonePool is the pool of the nodes without the label grouping the nodes
+	nonePool = "<none>"
+
+	ReasonLargerThanNode = "larger than a node"
+	ReasonPoolFull       = "pool is full"
+)
+
+// NodePool is a shape of nodes: the allocatable cpu, memory and pods of every node. MaxNodes limits the number of
+// nodes, 0 is unlimited, Nodes is the number of nodes of a live pool. The DaemonSets run on the nodes of a pool when
+// their placement matches its Labels and Taints.
+type NodePool struct {
+	Name     string            `json:"name" mapstructure:"name"`
+	CPU      string            `json:"cpu" mapstructure:"cpu"`
+	Memory   string            `json:"memory" mapstructure:"memory"`
+	Pods     int64             `json:"pods,omitempty" mapstructure:"pods"`
+	MaxNodes int               `json:"maxNodes,omitempty" mapstructure:"maxNodes"`
+	Nodes    int               `json:"nodes,omitempty" mapstructure:"nodes"`
+	Labels   map[string]string `json:"labels,omitempty" mapstructure:"labels"`
+	Taints   []v1.Taint        `json:"taints,omitempty" mapstructure:"taints"`
+}
+
+// UnschedulableItem is the number of pods of a controller which can not be placed in a pool, and why.
+type UnschedulableItem struct {
+	Namespace      string `json:"namespace"`
+	ControllerType string `json:"controllerType"`
+	Controller     string `json:"controller"`
+	Pods           int    `json:"pods"`
+	Reason         string `json:"reason"`
+}
+
+// PoolSimulation is the result of packing the controllers on the nodes of Pool. Requests include the DaemonSet
+// overhead of every node, Stranded is the allocatable left unrequested on the simulated nodes.
+type PoolSimulation struct {
+	Pool              NodePool            `json:"pool"`
+	Nodes             int                 `json:"nodes"`
+	DaemonSetOverhead v1.ResourceList     `json:"daemonSetOverhead"`
+	Allocatable       v1.ResourceList     `json:"allocatable"`
+	Requests          v1.ResourceList     `json:"requests"`
+	Stranded          v1.ResourceList     `json:"stranded"`
+	Unschedulable     []UnschedulableItem `json:"unschedulable,omitempty"`
+}
+
+// simulatedPod is a replica of a controller, cpu in millicores and memory in bytes.
+type simulatedPod struct {
+	item   *ControllerItem
+	cpu    int64
+	memory int64
+}
+
+// simulatedNode is the free capacity of a node, cpu in millicores and memory in bytes.
+type simulatedNode struct {
+	cpu    int64
+	memory int64
+	pods   int64
+}
+
+func (node simulatedNode) fits(pod simulatedPod) bool {
+	return node.cpu >= pod.cpu && node.memory >= pod.memory && node.pods >= 1
+}
+
+// nodeCapacity returns the allocatable of a node of pool.
+func (pool NodePool) nodeCapacity() (simulatedNode, error) {
+	cpu, err := resource.ParseQuantity(pool.CPU)
+	if err != nil {
+		return simulatedNode{}, fmt.Errorf("invalid cpu %q of node pool %q: %v", pool.CPU, pool.Name, err)
+	}
+	memory, err := resource.ParseQuantity(pool.Memory)
+	if err != nil {
+		return simulatedNode{}, fmt.Errorf("invalid memory %q of node pool %q: %v", pool.Memory, pool.Name, err)
+	}
+	if cpu.Sign() <= 0 || memory.Sign() <= 0 {
+		return simulatedNode{}, fmt.Errorf("node pool %q needs a positive cpu and memory", pool.Name)
+	}
+	pods := pool.Pods
+	if pods == 0 {
+		pods = DefaultNodePods
+	}
+	return simulatedNode{cpu: cpu.MilliValue(), memory: memory.Value(), pods: pods}, nil
+}
+
+// node returns a node of pool with its labels and taints.
+func (pool NodePool) node() *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: pool.Name, Labels: pool.Labels}, Spec: v1.NodeSpec{Taints: pool.Taints}}
+}
+
+// commonLabels returns the labels of both labels and newLabels with the same value.
+func commonLabels(labels, newLabels map[string]string) map[string]string {
+	result := map[string]string{}
+	for key, value := range labels {
+		if newValue, ok := newLabels[key]; ok && newValue == value {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// commonTaints returns the taints of both taints and newTaints.
+func commonTaints(taints, newTaints []v1.Taint) []v1.Taint {
+	var result []v1.Taint
+	for _, taint := range taints {
+		for _, newTaint := range newTaints {
+			if taint.MatchTaint(&newTaint) && taint.Value == newTaint.Value {
+				result = append(result, taint)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// ValidateNodePool checks the name and the allocatable of pool.
+func ValidateNodePool(pool NodePool) error {
+	if pool.Name == "" {
+		return fmt.Errorf("node pool has no name")
+	} else if pool.Pods < 0 || pool.MaxNodes < 0 {
+		return fmt.Errorf("negative pods or maxNodes of node pool %q", pool.Name)
+	}
+	_, err := pool.nodeCapacity()
+	return err
+}
+
+// poolResources are the resources of the shape of a node pool.
+var poolResources = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourcePods}
+
+// missingPoolResource returns the first resource of poolResources which node has no allocatable of.
+func missingPoolResource(node v1.Node) (v1.ResourceName, bool) {
+	for _, resourceName := range poolResources {
+		if _, ok := node.Status.Allocatable[resourceName]; !ok {
+			return resourceName, true
+		}
+	}
+	return "", false
+}
+
+// GetNodePools groups the nodes by the value of label, the shape of a pool is the smallest allocatable of its nodes,
+// so that every node can hold the simulated pods, and the labels and taints common to its nodes. The pools are sorted
+// by name. The nodes without allocatable cpu, memory or pods, e.g. not yet ready, are left out with a warning.
+func GetNodePools(ctx context.Context, clientset kubernetes.Interface, label string, options Options) ([]NodePool, error) {
+	pools := map[string]*NodePool{}
+	minimums := map[string]v1.ResourceList{}
+	err := listPages(ctx, "node", "", options, clientset.CoreV1().Nodes().List, func(nodeList *v1.NodeList) error {
+		for _, node := range nodeList.Items {
+			if resourceName, ok := missingPoolResource(node); ok {
+				klog.Warningf("node %s has no allocatable %s, it is left out of the node pools", node.Name, resourceName)
+				continue
+			}
+			name, ok := node.Labels[label]
+			if !ok {
+				name = nonePool
+			}
+			pool, ok := pools[name]
+			if !ok {
+				pool = &NodePool{Name: name, Labels: node.Labels, Taints: node.Spec.Taints}
+				pools[name] = pool
+				minimums[name] = node.Status.Allocatable.DeepCopy()
+			}
+			pool.Labels = commonLabels(pool.Labels, node.Labels)
+			pool.Taints = commonTaints(pool.Taints, node.Spec.Taints)
+			pool.Nodes++
+			for _, resourceName := range poolResources {
+				if quantity := node.Status.Allocatable[resourceName]; quantity.Cmp(minimums[name][resourceName]) < 0 {
+					minimums[name][resourceName] = quantity.DeepCopy()
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]NodePool, 0, len(pools))
+	for name, pool := range pools {
+		cpu, memory, pods := minimums[name][v1.ResourceCPU], minimums[name][v1.ResourceMemory], minimums[name][v1.ResourcePods]
+		pool.CPU = cpu.String()
+		pool.Memory = memory.String()
+		pool.Pods = pods.Value()
+		result = append(result, *pool)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// addUnschedulable counts a pod of item in unschedulable, the pods of a controller are consecutive.
+func addUnschedulable(unschedulable []UnschedulableItem, item *ControllerItem, reason string) []UnschedulableItem {
+	if last := len(unschedulable) - 1; last >= 0 {
+		previous := &unschedulable[last]
+		if previous.Namespace == item.Namespace && previous.ControllerType == item.ControllerType &&
+			previous.Controller == item.Controller && previous.Reason == reason {
+			previous.Pods++
+			return unschedulable
+		}
+	}
+	return append(unschedulable, UnschedulableItem{Namespace: item.Namespace, ControllerType: item.ControllerType,
+		Controller: item.Controller, Pods: 1, Reason: reason})
+}
+
+// simulatePool packs pods on the nodes of pool first fit decreasing, every node runs a pod of every DaemonSet whose
+// placement matches the pool.
+func simulatePool(pool NodePool, pods []simulatedPod, daemonSets []*ControllerItem) (PoolSimulation, error) {
+	capacity, err := pool.nodeCapacity()
+	if err != nil {
+		return PoolSimulation{}, err
+	}
+	simulation := PoolSimulation{Pool: pool, DaemonSetOverhead: v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(0, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(0, resource.BinarySI),
+		v1.ResourcePods:   *resource.NewQuantity(0, resource.DecimalSI),
+	}}
+	empty := capacity
+	poolNode := pool.node()
+	for _, item := range daemonSets {
+		if !item.Placement.Matches(poolNode) {
+			continue
+		}
+		empty.cpu -= item.EffectivePod.RequestCPU.MilliValue()
+		empty.memory -= item.EffectivePod.RequestMem.Value()
+		empty.pods--
+		addResourceList(simulation.DaemonSetOverhead, v1.ResourceList{
+			v1.ResourceCPU:    item.EffectivePod.RequestCPU,
+			v1.ResourceMemory: item.EffectivePod.RequestMem,
+			v1.ResourcePods:   *resource.NewQuantity(1, resource.DecimalSI),
+		})
+	}
+
+	// the largest pods first, by their largest share of a node
+	share := func(pod simulatedPod) float64 {
+		cpuShare, memoryShare := float64(pod.cpu)/float64(capacity.cpu), float64(pod.memory)/float64(capacity.memory)
+		if cpuShare > memoryShare {
+			return cpuShare
+		}
+		return memoryShare
+	}
+	sorted := append([]simulatedPod{}, pods...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return share(sorted[i]) > share(sorted[j])
+	})
+
+	var nodes []simulatedNode
+	for _, pod := range sorted {
+		placed := false
+		for index := range nodes {
+			if nodes[index].fits(pod) {
+				nodes[index].cpu -= pod.cpu
+				nodes[index].memory -= pod.memory
+				nodes[index].pods--
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+		if !empty.fits(pod) {
+			simulation.Unschedulable = addUnschedulable(simulation.Unschedulable, pod.item, ReasonLargerThanNode)
+		} else if pool.MaxNodes > 0 && len(nodes) >= pool.MaxNodes {
+			simulation.Unschedulable = addUnschedulable(simulation.Unschedulable, pod.item, ReasonPoolFull)
+		} else {
+			nodes = append(nodes, simulatedNode{cpu: empty.cpu - pod.cpu, memory: empty.memory - pod.memory, pods: empty.pods - 1})
+		}
+	}
+
+	simulation.Nodes = len(nodes)
+	count := int64(len(nodes))
+	simulation.Allocatable = v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(capacity.cpu*count, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(capacity.memory*count, resource.BinarySI),
+		v1.ResourcePods:   *resource.NewQuantity(capacity.pods*count, resource.DecimalSI),
+	}
+	stranded := simulatedNode{}
+	for _, node := range nodes {
+		stranded.cpu += node.cpu
+		stranded.memory += node.memory
+		stranded.pods += node.pods
+	}
+	simulation.Stranded = v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(stranded.cpu, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(stranded.memory, resource.BinarySI),
+		v1.ResourcePods:   *resource.NewQuantity(stranded.pods, resource.DecimalSI),
+	}
+	simulation.Requests = v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(capacity.cpu*count-stranded.cpu, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(capacity.memory*count-stranded.memory, resource.BinarySI),
+		v1.ResourcePods:   *resource.NewQuantity(capacity.pods*count-stranded.pods, resource.DecimalSI),
+	}
+	return simulation, nil
+}
+
+// Simulate packs every replica of items on the nodes of every pool separately, and returns how many nodes of each
+// pool are needed. The DaemonSets are not packed, their pods are the overhead of every node they match.
+func Simulate(items []ControllerItem, pools []NodePool) ([]PoolSimulation, error) {
+	var pods []simulatedPod
+	var daemonSets []*ControllerItem
+	for index := range items {
+		item := &items[index]
+		if item.ControllerType == TypeDaemonSet {
+			daemonSets = append(daemonSets, item)
+			continue
+		}
+		for replica := int32(0); replica < item.Replicas; replica++ {
+			pods = append(pods, simulatedPod{item: item, cpu: item.EffectivePod.RequestCPU.MilliValue(), memory: item.EffectivePod.RequestMem.Value()})
+		}
+	}
+	result := make([]PoolSimulation, 0, len(pools))
+	for _, pool := range pools {
+		simulation, err := simulatePool(pool, pods, daemonSets)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, simulation)
+	}
+	return result, nil
+}
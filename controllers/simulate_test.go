@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testSimulateItem(controllerType, name string, replicas int32, cpu, memory string) ControllerItem {
+	return ControllerItem{
+		Namespace:      "default",
+		ControllerType: controllerType,
+		Controller:     name,
+		Replicas:       replicas,
+		EffectivePod:   ResourceItem{RequestCPU: resource.MustParse(cpu), RequestMem: resource.MustParse(memory)},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	items := []ControllerItem{
+		testSimulateItem("Deployment", "web", 5, "1", "1Gi"),
+		testSimulateItem("Deployment", "cache", 2, "500m", "6Gi"),
+		testSimulateItem("Deployment", "huge", 2, "8", "1Gi"),
+		testSimulateItem("Daemonset", "agent", 1, "200m", "256Mi"),
+		testSimulateItem("Daemonset", "gpu-driver", 1, "1", "1Gi"),
+	}
+	items[3].Placement = &PlacementItem{}
+	// the driver runs on the gpu nodes only, the large nodes are tainted but not labeled
+	items[4].Placement = &PlacementItem{NodeSelector: map[string]string{"gpu": "true"},
+		Tolerations: []v1.Toleration{{Key: "gpu", Operator: v1.TolerationOpExists}}}
+	pools := []NodePool{
+		{Name: "small", CPU: "4", Memory: "8Gi"},
+		{Name: "large", CPU: "16", Memory: "32Gi", Pods: 4, MaxNodes: 1, Taints: []v1.Taint{{Key: "gpu", Effect: v1.TaintEffectNoSchedule}}},
+	}
+	result, err := Simulate(items, pools)
+	if err != nil || len(result) != 2 {
+		t.Fatalf("Simulate() = %+v, %v", result, err)
+	}
+
+	// a node holds 3.8 cpu and 7.75Gi after the agent: each cache shares a node with a web pod, 3 web pods fill the third
+	small := result[0]
+	if small.Nodes != 3 {
+		t.Errorf("small nodes = %d, want 3", small.Nodes)
+	}
+	overhead := small.DaemonSetOverhead[v1.ResourceCPU]
+	if overhead.Cmp(resource.MustParse("200m")) != 0 {
+		t.Errorf("small daemonset overhead = %v, want 200m", small.DaemonSetOverhead)
+	}
+	requests, stranded := small.Requests[v1.ResourceCPU], small.Stranded[v1.ResourceCPU]
+	if requests.Cmp(resource.MustParse("6600m")) != 0 || stranded.Cmp(resource.MustParse("5400m")) != 0 {
+		t.Errorf("small cpu requests = %s, stranded = %s, want 6600m, 5400m", requests.String(), stranded.String())
+	}
+	if len(small.Unschedulable) != 1 || small.Unschedulable[0].Controller != "huge" || small.Unschedulable[0].Pods != 2 ||
+		small.Unschedulable[0].Reason != ReasonLargerThanNode {
+		t.Errorf("small unschedulable = %+v, want 2 huge pods larger than a node", small.Unschedulable)
+	}
+
+	// neither DaemonSet runs on the tainted node, which holds both huge pods
+	large := result[1]
+	if large.Nodes != 1 || len(large.Unschedulable) == 0 || large.Unschedulable[len(large.Unschedulable)-1].Reason != ReasonPoolFull {
+		t.Errorf("large = %+v, want a full node", large)
+	}
+	pods := 0
+	for _, item := range large.Unschedulable {
+		pods += item.Pods
+	}
+	if pods != 7 || !large.DaemonSetOverhead.Cpu().IsZero() {
+		t.Errorf("large unschedulable pods = %d, daemonset overhead = %v, want 7 and none", pods, large.DaemonSetOverhead)
+	}
+
+	for _, pool := range []NodePool{{CPU: "1", Memory: "1Gi"}, {Name: "cpu", CPU: "one", Memory: "1Gi"}, {Name: "zero", CPU: "0", Memory: "1Gi"}} {
+		if err := ValidateNodePool(pool); err == nil {
+			t.Errorf("ValidateNodePool(%+v) returns no error", pool)
+		}
+	}
+}
+
+func TestGetNodePools(t *testing.T) {
+	gpuTaint := v1.Taint{Key: "gpu", Value: "true", Effect: v1.TaintEffectNoSchedule}
+	first := testNode("a-1", map[string]string{"pool": "a", "zone": "1"}, "4", "8Gi", 0)
+	first.Spec.Taints = []v1.Taint{gpuTaint, {Key: "draining", Effect: v1.TaintEffectNoSchedule}}
+	second := testNode("a-2", map[string]string{"pool": "a", "zone": "2"}, "3900m", "16Gi", 0)
+	second.Spec.Taints = []v1.Taint{gpuTaint}
+	// a node without allocatable pods, e.g. not yet ready, is left out of its pool
+	partial := testNode("a-3", map[string]string{"pool": "a"}, "1", "2Gi", 0)
+	delete(partial.Status.Allocatable, v1.ResourcePods)
+	clientset := fake.NewSimpleClientset(first, second, partial, testNode("other", nil, "2", "4Gi", 0))
+	pools, err := GetNodePools(context.Background(), clientset, "pool", Options{})
+	if err != nil || len(pools) != 2 {
+		t.Fatalf("GetNodePools() = %+v, %v", pools, err)
+	}
+	want := []NodePool{
+		{Name: nonePool, CPU: "2", Memory: "4Gi", Pods: 110, Nodes: 1, Labels: map[string]string{}},
+		// the smallest allocatable, and the labels and taints of both nodes
+		{Name: "a", CPU: "3900m", Memory: "8Gi", Pods: 110, Nodes: 2, Labels: map[string]string{"pool": "a"}, Taints: []v1.Taint{gpuTaint}},
+	}
+	if !reflect.DeepEqual(pools, want) {
+		t.Errorf("GetNodePools() = %+v, want %+v", pools, want)
+	}
+}
@@ -2,46 +2,26 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/klog/v2"
 )
 
-func getStatefulsetItems(clientset *kubernetes.Clientset, namespaces []string, debugInfo bool) ([]ControllerItem, error) {
+func getStatefulsetItems(ctx context.Context, clientset kubernetes.Interface, namespace string, info *clusterInfo, options Options) ([]ControllerItem, error) {
 	var result []ControllerItem
-	for _, namespace := range namespaces {
-		controllerClient := clientset.AppsV1().StatefulSets(namespace)
-		controllers, err := controllerClient.List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			return nil, nil
-		}
-		if debugInfo {
-			if controllerJson, err := json.Marshal(controllers); err != nil {
-				return nil, nil
-			} else {
-				klog.Infof("namespaces %s statefulset:\n%s", namespace, controllerJson)
-			}
-		}
+	err := listPages(ctx, "statefulset", namespace, options, clientset.AppsV1().StatefulSets(namespace).List, func(controllers *appsv1.StatefulSetList) error {
 		for _, controller := range controllers.Items {
 			controllerItem := ControllerItem{
 				Namespace:      controller.Namespace,
-				ControllerType: "Statefulset",
+				ControllerType: TypeStatefulSet,
 				Controller:     controller.Name,
-				Replicas:       *controller.Spec.Replicas,
+				Replicas:       specReplicas(controller.Spec.Replicas),
 			}
-			emptyDir, storage, storageNoSize, memStorage := generateVolumeResult(controller.Spec.Template.Spec.Volumes)
-			if memStorage {
-				klog.Infof("memory EmptyDir, namespace: %q, %s: %q", controllerItem.Namespace, controllerItem.ControllerType, controllerItem.Controller)
-			}
-			controllerItem.EmptyDir = emptyDir
-			controllerItem.Storage = storage
-			controllerItem.StorageNoSize = storageNoSize
-
-			controllerItem.Container = generateContainers(controller.Spec.Template.Spec.Containers)
-			controllerItem.InitContainer = generateContainers(controller.Spec.Template.Spec.InitContainers)
+			info.generatePodTemplateItem(&controllerItem, controller.Spec.Template)
+			info.generateClaimTemplateStorage(&controllerItem, controller.Spec.VolumeClaimTemplates)
+			controllerItem.setStatefulSetRollout(controller.Spec.UpdateStrategy)
 			result = append(result, controllerItem)
 		}
-	}
-	return result, nil
+		return nil
+	})
+	return result, err
 }
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+	// noStorageClass is reported for claims which explicitly request no storage class
+	noStorageClass = "<none>"
+	// unknownStorageClass is reported for claims using the default storage class when the cluster has none
+	unknownStorageClass = "<default>"
+)
+
+// defaultCSISizeAttributes are the volumeAttributes keys holding the size of an inline CSI volume, used for the
+// drivers which are not configured.
+var defaultCSISizeAttributes = []string{"size", "capacity", "storage"}
+
+// csiVolumeSize returns the size of an inline CSI volume found in its volumeAttributes, nil if it is not found.
+// hasStorage is false for the drivers configured without size keys, e.g. secrets-store.csi.k8s.io.
+func csiVolumeSize(volume v1.CSIVolumeSource, csiSizeAttributes map[string][]string) (size *resource.Quantity, hasStorage bool, err error) {
+	keys, ok := csiSizeAttributes[volume.Driver]
+	if !ok {
+		keys = defaultCSISizeAttributes
+	} else if len(keys) == 0 {
+		return nil, false, nil
+	}
+	for _, key := range keys {
+		if value, ok := volume.VolumeAttributes[key]; ok {
+			quantity, err := resource.ParseQuantity(value)
+			if err != nil {
+				return nil, true, fmt.Errorf("attribute %q of driver %q: %v", key, volume.Driver, err)
+			}
+			return &quantity, true, nil
+		}
+	}
+	return nil, true, nil
+}
+
+// StorageClassItem is the persistent storage of a controller in one storage class.
+type StorageClassItem struct {
+	StorageClass string            `json:"storageClass"`
+	Claims       int32             `json:"claims"`
+	Request      resource.Quantity `json:"request"`
+	Capacity     resource.Quantity `json:"capacity"`
+}
+
+func getDefaultStorageClass(ctx context.Context, clientset kubernetes.Interface) (string, error) {
+	storageClasses, err := clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return unknownStorageClass, err
+	}
+	for _, storageClass := range storageClasses.Items {
+		if storageClass.Annotations[defaultStorageClassAnnotation] == "true" || storageClass.Annotations[betaDefaultStorageClassAnnotation] == "true" {
+			return storageClass.Name, nil
+		}
+	}
+	return unknownStorageClass, nil
+}
+
+// getClaims adds the persistentvolumeclaims of namespace, or of all namespaces if it is empty, to claims.
+func getClaims(ctx context.Context, clientset kubernetes.Interface, namespace string, options Options, claims map[string]map[string]v1.PersistentVolumeClaim) error {
+	return listPages(ctx, "persistentvolumeclaim", namespace, Options{PageSize: options.PageSize}, clientset.CoreV1().PersistentVolumeClaims(namespace).List,
+		func(claimList *v1.PersistentVolumeClaimList) error {
+			for _, claim := range claimList.Items {
+				if claims[claim.Namespace] == nil {
+					claims[claim.Namespace] = map[string]v1.PersistentVolumeClaim{}
+				}
+				claims[claim.Namespace][claim.Name] = claim
+			}
+			return nil
+		})
+}
+
+func (info *clusterInfo) storageClassName(claimSpec v1.PersistentVolumeClaimSpec) string {
+	if claimSpec.StorageClassName == nil {
+		return info.defaultStorageClass
+	} else if *claimSpec.StorageClassName == "" {
+		return noStorageClass
+	}
+	return *claimSpec.StorageClassName
+}
+
+func (info *clusterInfo) getClaim(namespace, name string) (v1.PersistentVolumeClaim, bool) {
+	claim, ok := info.claims[namespace][name]
+	return claim, ok
+}
+
+func claimCapacity(claim v1.PersistentVolumeClaim) resource.Quantity {
+	if claim.Status.Phase != v1.ClaimBound {
+		return resource.Quantity{}
+	}
+	return claim.Status.Capacity.Storage().DeepCopy()
+}
+
+func addStorageClassItem(items []StorageClassItem, item StorageClassItem) []StorageClassItem {
+	for i := range items {
+		if items[i].StorageClass == item.StorageClass {
+			items[i].Claims += item.Claims
+			items[i].Request.Add(item.Request)
+			items[i].Capacity.Add(item.Capacity)
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+// generateClaimStorage returns the storage of the claims created from claimSpec for every replica, the capacity of
+// the claims already bound is resolved by claimNames.
+func (info *clusterInfo) generateClaimStorage(namespace string, claimSpec v1.PersistentVolumeClaimSpec, claims int32, claimNames []string) StorageClassItem {
+	item := StorageClassItem{
+		StorageClass: info.storageClassName(claimSpec),
+		Claims:       claims,
+		Request:      claimSpec.Resources.Requests.Storage().DeepCopy(),
+	}
+	item.Request.Mul(int64(claims))
+	for _, claimName := range claimNames {
+		if claim, ok := info.getClaim(namespace, claimName); ok {
+			item.Capacity.Add(claimCapacity(claim))
+		}
+	}
+	return item
+}
+
+// generatePersistentStorage adds the persistentVolumeClaim and generic ephemeral volumes of the pod template to
+// controllerItem. A referenced claim is shared by every replica, an ephemeral volume creates one claim per replica.
+func (info *clusterInfo) generatePersistentStorage(controllerItem *ControllerItem, volumes []v1.Volume) {
+	for _, volume := range volumes {
+		if volume.PersistentVolumeClaim != nil {
+			claim, ok := info.getClaim(controllerItem.Namespace, volume.PersistentVolumeClaim.ClaimName)
+			if !ok {
+				klog.Warningf("persistentvolumeclaim %q not found, namespace: %q, %s: %q", volume.PersistentVolumeClaim.ClaimName,
+					controllerItem.Namespace, controllerItem.ControllerType, controllerItem.Controller)
+				controllerItem.StorageNoSize = true
+				continue
+			}
+			controllerItem.PersistentStorage = addStorageClassItem(controllerItem.PersistentStorage, StorageClassItem{
+				StorageClass: info.storageClassName(claim.Spec),
+				Claims:       1,
+				Request:      claim.Spec.Resources.Requests.Storage().DeepCopy(),
+				Capacity:     claimCapacity(claim),
+			})
+		}
+		if volume.Ephemeral != nil && volume.Ephemeral.VolumeClaimTemplate != nil {
+			// the claims of ephemeral volumes are named after pods, which are only known for bare pods
+			var claimNames []string
+			if controllerItem.ControllerType == TypePod {
+				claimNames = []string{fmt.Sprintf("%s-%s", controllerItem.Controller, volume.Name)}
+			}
+			controllerItem.PersistentStorage = addStorageClassItem(controllerItem.PersistentStorage,
+				info.generateClaimStorage(controllerItem.Namespace, volume.Ephemeral.VolumeClaimTemplate.Spec, controllerItem.Replicas, claimNames))
+		}
+	}
+}
+
+// generateClaimTemplateStorage adds the volumeClaimTemplates of a statefulset to controllerItem, the claim of every
+// replica is named <template>-<statefulset>-<ordinal>.
+func (info *clusterInfo) generateClaimTemplateStorage(controllerItem *ControllerItem, claimTemplates []v1.PersistentVolumeClaim) {
+	for _, claimTemplate := range claimTemplates {
+		claimNames := make([]string, 0, controllerItem.Replicas)
+		for ordinal := int32(0); ordinal < controllerItem.Replicas; ordinal++ {
+			claimNames = append(claimNames, fmt.Sprintf("%s-%s-%d", claimTemplate.Name, controllerItem.Controller, ordinal))
+		}
+		controllerItem.PersistentStorage = addStorageClassItem(controllerItem.PersistentStorage,
+			info.generateClaimStorage(controllerItem.Namespace, claimTemplate.Spec, controllerItem.Replicas, claimNames))
+	}
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func quantityPtr(value string) *resource.Quantity {
+	quantity := resource.MustParse(value)
+	return &quantity
+}
+
+func csiVolume(name, driver string, attributes map[string]string) v1.Volume {
+	return v1.Volume{Name: name, VolumeSource: v1.VolumeSource{CSI: &v1.CSIVolumeSource{Driver: driver, VolumeAttributes: attributes}}}
+}
+
+func claimSpec(storageClass *string, request string) v1.PersistentVolumeClaimSpec {
+	return v1.PersistentVolumeClaimSpec{
+		StorageClassName: storageClass,
+		Resources:        v1.VolumeResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(request)}},
+	}
+}
+
+func boundClaim(name, storageClass, request, capacity string) v1.PersistentVolumeClaim {
+	return v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       claimSpec(&storageClass, request),
+		Status: v1.PersistentVolumeClaimStatus{
+			Phase:    v1.ClaimBound,
+			Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse(capacity)},
+		},
+	}
+}
+
+func equalStorageClassItems(items, want []StorageClassItem) bool {
+	if len(items) != len(want) {
+		return false
+	}
+	for i := range items {
+		if items[i].StorageClass != want[i].StorageClass || items[i].Claims != want[i].Claims ||
+			items[i].Request.Cmp(want[i].Request) != 0 || items[i].Capacity.Cmp(want[i].Capacity) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCSIVolumeSize(t *testing.T) {
+	csiSizeAttributes := map[string][]string{
+		"topolvm.io":               {"topolvm.io/size"},
+		"secrets-store.csi.k8s.io": {},
+	}
+	tests := []struct {
+		name       string
+		volume     v1.CSIVolumeSource
+		size       *resource.Quantity
+		hasStorage bool
+		wantErr    bool
+	}{
+		{
+			name:       "default size key",
+			volume:     v1.CSIVolumeSource{Driver: "hostpath.csi.k8s.io", VolumeAttributes: map[string]string{"size": "1Gi"}},
+			size:       quantityPtr("1Gi"),
+			hasStorage: true,
+		},
+		{
+			name:       "default capacity key",
+			volume:     v1.CSIVolumeSource{Driver: "hostpath.csi.k8s.io", VolumeAttributes: map[string]string{"capacity": "500M"}},
+			size:       quantityPtr("500M"),
+			hasStorage: true,
+		},
+		{
+			name:       "configured driver key",
+			volume:     v1.CSIVolumeSource{Driver: "topolvm.io", VolumeAttributes: map[string]string{"topolvm.io/size": "2Gi", "size": "1Gi"}},
+			size:       quantityPtr("2Gi"),
+			hasStorage: true,
+		},
+		{
+			name:       "configured driver without size",
+			volume:     v1.CSIVolumeSource{Driver: "topolvm.io", VolumeAttributes: map[string]string{"size": "1Gi"}},
+			hasStorage: true,
+		},
+		{
+			name:   "driver without storage",
+			volume: v1.CSIVolumeSource{Driver: "secrets-store.csi.k8s.io", VolumeAttributes: map[string]string{"secretProviderClass": "vault"}},
+		},
+		{
+			name:       "unknown driver",
+			volume:     v1.CSIVolumeSource{Driver: "unknown.csi.k8s.io"},
+			hasStorage: true,
+		},
+		{
+			name:       "invalid size",
+			volume:     v1.CSIVolumeSource{Driver: "hostpath.csi.k8s.io", VolumeAttributes: map[string]string{"size": "large"}},
+			hasStorage: true,
+			wantErr:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			size, hasStorage, err := csiVolumeSize(test.volume, csiSizeAttributes)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("csiVolumeSize() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if hasStorage != test.hasStorage {
+				t.Errorf("csiVolumeSize() hasStorage = %v, want %v", hasStorage, test.hasStorage)
+			}
+			if (size == nil) != (test.size == nil) || (size != nil && size.Cmp(*test.size) != 0) {
+				t.Errorf("csiVolumeSize() size = %v, want %v", size, test.size)
+			}
+		})
+	}
+}
+
+func TestGenerateVolumeResult(t *testing.T) {
+	csiSizeAttributes := map[string][]string{"secrets-store.csi.k8s.io": {}}
+	tests := []struct {
+		name    string
+		volumes []v1.Volume
+		want    volumeResult
+	}{
+		{
+			name: "no volumes",
+		},
+		{
+			name: "emptyDir",
+			volumes: []v1.Volume{
+				{Name: "a", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{SizeLimit: quantityPtr("1Gi")}}},
+				{Name: "b", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{SizeLimit: quantityPtr("512Mi")}}},
+			},
+			want: volumeResult{emptyDir: resource.MustParse("1536Mi")},
+		},
+		{
+			name: "emptyDir without size limit",
+			volumes: []v1.Volume{
+				{Name: "a", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+			},
+			want: volumeResult{storageNoSize: true},
+		},
+		{
+			name: "memory emptyDir",
+			volumes: []v1.Volume{
+				{Name: "a", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory, SizeLimit: quantityPtr("1Gi")}}},
+			},
+			want: volumeResult{memStorage: true},
+		},
+		{
+			name: "inline csi",
+			volumes: []v1.Volume{
+				csiVolume("a", "hostpath.csi.k8s.io", map[string]string{"size": "1Gi"}),
+				csiVolume("b", "hostpath.csi.k8s.io", map[string]string{"capacity": "2Gi"}),
+				csiVolume("c", "secrets-store.csi.k8s.io", nil),
+			},
+			want: volumeResult{storage: resource.MustParse("3Gi")},
+		},
+		{
+			name: "inline csi without size",
+			volumes: []v1.Volume{
+				csiVolume("a", "hostpath.csi.k8s.io", map[string]string{"size": "1Gi"}),
+				csiVolume("b", "unknown.csi.k8s.io", nil),
+				csiVolume("c", "hostpath.csi.k8s.io", map[string]string{"size": "large"}),
+			},
+			want: volumeResult{storage: resource.MustParse("1Gi"), storageNoSize: true, unknownVolumes: []string{"b(unknown.csi.k8s.io)", "c(hostpath.csi.k8s.io)"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := generateVolumeResult(test.volumes, csiSizeAttributes)
+			if got.emptyDir.Cmp(test.want.emptyDir) != 0 || got.storage.Cmp(test.want.storage) != 0 ||
+				got.storageNoSize != test.want.storageNoSize || got.memStorage != test.want.memStorage ||
+				!reflect.DeepEqual(got.unknownVolumes, test.want.unknownVolumes) {
+				t.Errorf("generateVolumeResult() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGeneratePersistentStorage(t *testing.T) {
+	fast, none := "fast", ""
+	info := &clusterInfo{
+		defaultStorageClass: "standard",
+		claims: map[string]map[string]v1.PersistentVolumeClaim{
+			"default": {
+				"shared":        boundClaim("shared", "fast", "10Gi", "16Gi"),
+				"data-web-0":    boundClaim("data-web-0", "standard", "1Gi", "1Gi"),
+				"data-web-1":    boundClaim("data-web-1", "standard", "1Gi", "2Gi"),
+				"pod-a-scratch": boundClaim("pod-a-scratch", "fast", "1Gi", "1Gi"),
+			},
+		},
+	}
+	tests := []struct {
+		name           string
+		controllerItem ControllerItem
+		volumes        []v1.Volume
+		claimTemplates []v1.PersistentVolumeClaim
+		want           []StorageClassItem
+		storageNoSize  bool
+	}{
+		{
+			name:           "shared claim is counted once",
+			controllerItem: ControllerItem{Namespace: "default", ControllerType: "Deployment", Controller: "web", Replicas: 3},
+			volumes: []v1.Volume{
+				{Name: "data", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "shared"}}},
+			},
+			want: []StorageClassItem{{StorageClass: "fast", Claims: 1, Request: resource.MustParse("10Gi"), Capacity: resource.MustParse("16Gi")}},
+		},
+		{
+			name:           "missing claim",
+			controllerItem: ControllerItem{Namespace: "default", ControllerType: "Deployment", Controller: "web", Replicas: 3},
+			volumes: []v1.Volume{
+				{Name: "data", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "missing"}}},
+			},
+			storageNoSize: true,
+		},
+		{
+			name:           "ephemeral volume per replica",
+			controllerItem: ControllerItem{Namespace: "default", ControllerType: "Deployment", Controller: "web", Replicas: 3},
+			volumes: []v1.Volume{
+				{Name: "scratch", VolumeSource: v1.VolumeSource{Ephemeral: &v1.EphemeralVolumeSource{
+					VolumeClaimTemplate: &v1.PersistentVolumeClaimTemplate{Spec: claimSpec(nil, "2Gi")}}}},
+				{Name: "cache", VolumeSource: v1.VolumeSource{Ephemeral: &v1.EphemeralVolumeSource{
+					VolumeClaimTemplate: &v1.PersistentVolumeClaimTemplate{Spec: claimSpec(&none, "1Gi")}}}},
+			},
+			want: []StorageClassItem{
+				{StorageClass: "standard", Claims: 3, Request: resource.MustParse("6Gi")},
+				{StorageClass: noStorageClass, Claims: 3, Request: resource.MustParse("3Gi")},
+			},
+		},
+		{
+			name:           "ephemeral volume of a pod",
+			controllerItem: ControllerItem{Namespace: "default", ControllerType: "Pod", Controller: "pod-a", Replicas: 1},
+			volumes: []v1.Volume{
+				{Name: "scratch", VolumeSource: v1.VolumeSource{Ephemeral: &v1.EphemeralVolumeSource{
+					VolumeClaimTemplate: &v1.PersistentVolumeClaimTemplate{Spec: claimSpec(&fast, "1Gi")}}}},
+			},
+			want: []StorageClassItem{{StorageClass: "fast", Claims: 1, Request: resource.MustParse("1Gi"), Capacity: resource.MustParse("1Gi")}},
+		},
+		{
+			name:           "statefulset claim templates",
+			controllerItem: ControllerItem{Namespace: "default", ControllerType: "Statefulset", Controller: "web", Replicas: 3},
+			claimTemplates: []v1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "data"}, Spec: claimSpec(nil, "1Gi")},
+				{ObjectMeta: metav1.ObjectMeta{Name: "logs"}, Spec: claimSpec(&fast, "512Mi")},
+			},
+			want: []StorageClassItem{
+				{StorageClass: "standard", Claims: 3, Request: resource.MustParse("3Gi"), Capacity: resource.MustParse("3Gi")},
+				{StorageClass: "fast", Claims: 3, Request: resource.MustParse("1536Mi")},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			controllerItem := test.controllerItem
+			info.generatePersistentStorage(&controllerItem, test.volumes)
+			info.generateClaimTemplateStorage(&controllerItem, test.claimTemplates)
+			if !equalStorageClassItems(controllerItem.PersistentStorage, test.want) {
+				t.Errorf("PersistentStorage = %+v, want %+v", controllerItem.PersistentStorage, test.want)
+			}
+			if controllerItem.StorageNoSize != test.storageNoSize {
+				t.Errorf("StorageNoSize = %v, want %v", controllerItem.StorageNoSize, test.storageNoSize)
+			}
+		})
+	}
+}
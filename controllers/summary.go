@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sort"
+	"strings"
+)
+
+const (
+	GroupByNamespace   = "namespace"
+	GroupByType        = "type"
+	GroupBySource      = "source"
+	GroupByLabelPrefix = "label:"
+	// noLabelGroup is the group of the controllers without the label or the source
+	noLabelGroup = "<none>"
+)
+
+// GroupItem sums the controllers of a group, all quantities are multiplied by the replicas.
+type GroupItem struct {
+	GroupBy     string `json:"groupBy"`
+	Group       string `json:"group"`
+	Controllers int    `json:"controllers"`
+	Replicas    int32  `json:"replicas"`
+	// Total sums the Total of the controllers
+	Total    ResourceItem      `json:"total"`
+	EmptyDir resource.Quantity `json:"emptyDir"`
+	Storage  resource.Quantity `json:"storage"`
+	// PersistentStorage sums the persistent storage requests of all storage classes
+	PersistentStorage resource.Quantity `json:"persistentStorage"`
+	// Autoscalers counts the controllers with an autoscaler, MaxReplicas and MaxTotal sum the replicas and footprints
+	// of the controllers at the max scale of their autoscalers, which are the current ones without autoscaler
+	Autoscalers int          `json:"autoscalers,omitempty"`
+	MaxReplicas int32        `json:"maxReplicas"`
+	MaxTotal    ResourceItem `json:"maxTotal"`
+	// PeakReplicas and PeakTotal sum the replicas and footprints of the controllers at the peak of their rollouts, the
+	// peak of the group if every controller rolled at once
+	PeakReplicas int32        `json:"peakReplicas"`
+	PeakTotal    ResourceItem `json:"peakTotal"`
+}
+
+// ValidateGroupBy checks groupBy is namespace, type, source or label:<key>.
+func ValidateGroupBy(groupBy string) error {
+	if groupBy == GroupByNamespace || groupBy == GroupByType || groupBy == GroupBySource {
+		return nil
+	} else if strings.HasPrefix(groupBy, GroupByLabelPrefix) && len(groupBy) > len(GroupByLabelPrefix) {
+		return nil
+	}
+	return fmt.Errorf("unknown group by %q, it must be %s, %s, %s or %s<key>", groupBy, GroupByNamespace, GroupByType, GroupBySource, GroupByLabelPrefix)
+}
+
+func groupOf(controllerItem ControllerItem, groupBy string) string {
+	if groupBy == GroupByNamespace {
+		return controllerItem.Namespace
+	} else if groupBy == GroupByType {
+		return controllerItem.ControllerType
+	} else if groupBy == GroupBySource {
+		if controllerItem.Source == "" {
+			return noLabelGroup
+		}
+		return controllerItem.Source
+	} else if value, ok := controllerItem.Labels[strings.TrimPrefix(groupBy, GroupByLabelPrefix)]; ok {
+		return value
+	}
+	return noLabelGroup
+}
+
+func multiplyQuantity(quantity resource.Quantity, replicas int32) resource.Quantity {
+	result := quantity.DeepCopy()
+	result.Mul(int64(replicas))
+	return result
+}
+
+// AggregateControllerItems sums items by groupBy, which is checked by ValidateGroupBy. The groups are sorted by name.
+func AggregateControllerItems(items []ControllerItem, groupBy string) []GroupItem {
+	groups := map[string]*GroupItem{}
+	for _, item := range items {
+		name := groupOf(item, groupBy)
+		group, ok := groups[name]
+		if !ok {
+			group = &GroupItem{GroupBy: groupBy, Group: name}
+			groups[name] = group
+		}
+		group.Controllers++
+		group.Replicas += item.Replicas
+		addResourceItem(&group.Total, item.Total)
+		group.EmptyDir.Add(multiplyQuantity(item.EmptyDir, item.Replicas))
+		group.Storage.Add(multiplyQuantity(item.Storage, item.Replicas))
+		for _, storageClassItem := range item.PersistentStorage {
+			group.PersistentStorage.Add(storageClassItem.Request)
+		}
+		if item.Autoscaler != nil {
+			group.Autoscalers++
+		}
+		group.MaxReplicas += item.MaxReplicas()
+		addResourceItem(&group.MaxTotal, item.MaxTotal())
+		group.PeakReplicas += item.PeakReplicas()
+		addResourceItem(&group.PeakTotal, item.PeakTotal())
+	}
+	result := make([]GroupItem, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Group < result[j].Group
+	})
+	return result
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAggregateControllerItems(t *testing.T) {
+	web := testDiffItem("shop", "Deployment", "web", 2, map[string]string{"app": "500m"})
+	web.Labels = map[string]string{"team": "sales"}
+	web.EmptyDir = resource.MustParse("1Gi")
+	web.PersistentStorage = []StorageClassItem{{StorageClass: "standard", Claims: 2, Request: resource.MustParse("20Gi")}}
+	api := testDiffItem("default", "Deployment", "api", 1, map[string]string{"app": "1"})
+	api.Labels = map[string]string{"team": "sales"}
+	api.Source = "overlays/prod"
+	job := testDiffItem("default", "Job", "migrate", 1, map[string]string{"migrate": "250m"})
+	items := []ControllerItem{web, api, job}
+
+	tests := []struct {
+		groupBy string
+		want    map[string]string
+	}{
+		{GroupByNamespace, map[string]string{"default": "1250m", "shop": "1"}},
+		{GroupByType, map[string]string{"Deployment": "2", "Job": "250m"}},
+		{"label:team", map[string]string{"sales": "2", noLabelGroup: "250m"}},
+		{GroupBySource, map[string]string{"overlays/prod": "1", noLabelGroup: "1250m"}},
+	}
+	for _, test := range tests {
+		if err := ValidateGroupBy(test.groupBy); err != nil {
+			t.Errorf("ValidateGroupBy(%q) error = %v", test.groupBy, err)
+		}
+		groups := AggregateControllerItems(items, test.groupBy)
+		if len(groups) != len(test.want) {
+			t.Errorf("AggregateControllerItems(%q) returns %d groups, want %d", test.groupBy, len(groups), len(test.want))
+		}
+		for _, group := range groups {
+			if want, ok := test.want[group.Group]; !ok || group.Total.RequestCPU.Cmp(resource.MustParse(want)) != 0 {
+				t.Errorf("AggregateControllerItems(%q) group %q requests %s cpu, want %s", test.groupBy, group.Group, group.Total.RequestCPU.String(), want)
+			}
+			if group.GroupBy != test.groupBy {
+				t.Errorf("group %q has groupBy %q, want %q", group.Group, group.GroupBy, test.groupBy)
+			}
+		}
+	}
+
+	groups := AggregateControllerItems(items, GroupByNamespace)
+	shop := groups[1]
+	if shop.Group != "shop" || shop.Controllers != 1 || shop.Replicas != 2 {
+		t.Fatalf("groups[1] = %+v, want shop with 1 controller and 2 replicas", shop)
+	}
+	if shop.EmptyDir.Cmp(resource.MustParse("2Gi")) != 0 || shop.PersistentStorage.Cmp(resource.MustParse("20Gi")) != 0 {
+		t.Errorf("shop storage = %s emptyDir, %s persistent, want 2Gi, 20Gi", shop.EmptyDir.String(), shop.PersistentStorage.String())
+	}
+
+	for _, groupBy := range []string{"", "label:", "owner"} {
+		if err := ValidateGroupBy(groupBy); err == nil {
+			t.Errorf("ValidateGroupBy(%q) returns no error", groupBy)
+		}
+	}
+}
+
+func TestNamespaceLabels(t *testing.T) {
+	template := testTemplate("100m", "128Mi")
+	template.Labels = map[string]string{"app": "web", "team": "web-team"}
+	clientset := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "platform", "cost-center": "42"}}},
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(1), Template: template}},
+	)
+	for _, namespaceLabels := range []bool{false, true} {
+		result, collectErrors, err := GetControllerItems(context.Background(), clientset, nil, Options{NamespaceLabels: namespaceLabels})
+		if err != nil || len(collectErrors) > 0 || len(result) != 1 {
+			t.Fatalf("GetControllerItems() = %v, %v, %v", result, collectErrors, err)
+		}
+		labels := result[0].Labels
+		// the pod template labels override the namespace labels
+		if labels["app"] != "web" || labels["team"] != "web-team" {
+			t.Errorf("labels = %v, want the pod template labels", labels)
+		}
+		if _, ok := labels["cost-center"]; ok != namespaceLabels {
+			t.Errorf("labels = %v with NamespaceLabels %v", labels, namespaceLabels)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// PodMetricsResource is the PodMetrics resource of the metrics API, which is served by metrics-server.
+var PodMetricsResource = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+
+// UsageItem is the current usage reported by the metrics API. The ratios divide the usage by the requests and limits,
+// they are nil when the request or limit is not set.
+type UsageItem struct {
+	CPU    resource.Quantity `json:"cpu"`
+	Memory resource.Quantity `json:"memory"`
+	// Pods is the number of pods with metrics
+	Pods               int      `json:"pods"`
+	CPURequestRatio    *float64 `json:"cpuRequestRatio,omitempty"`
+	CPULimitRatio      *float64 `json:"cpuLimitRatio,omitempty"`
+	MemoryRequestRatio *float64 `json:"memoryRequestRatio,omitempty"`
+	MemoryLimitRatio   *float64 `json:"memoryLimitRatio,omitempty"`
+}
+
+func usageRatio(usage, base int64) *float64 {
+	if base == 0 {
+		return nil
+	}
+	ratio := float64(usage) / float64(base)
+	return &ratio
+}
+
+func newUsageItem(cpu, memory resource.Quantity, pods int, resourceItem ResourceItem) *UsageItem {
+	return &UsageItem{
+		CPU:                cpu,
+		Memory:             memory,
+		Pods:               pods,
+		CPURequestRatio:    usageRatio(cpu.MilliValue(), resourceItem.RequestCPU.MilliValue()),
+		CPULimitRatio:      usageRatio(cpu.MilliValue(), resourceItem.LimitCPU.MilliValue()),
+		MemoryRequestRatio: usageRatio(memory.Value(), resourceItem.RequestMem.Value()),
+		MemoryLimitRatio:   usageRatio(memory.Value(), resourceItem.LimitMem.Value()),
+	}
+}
+
+type containerUsage struct {
+	pods        int
+	cpu, memory resource.Quantity
+}
+
+func (usage *containerUsage) add(resources v1.ResourceList) {
+	usage.pods++
+	usage.cpu.Add(*resources.Cpu())
+	usage.memory.Add(*resources.Memory())
+}
+
+// controllerUsage sums the usage of the pods of a controller, and by container name.
+type controllerUsage struct {
+	containerUsage
+	containers map[string]*containerUsage
+}
+
+func (usage *controllerUsage) addPod(podMetrics metricsv1beta1.PodMetrics) {
+	podResources := v1.ResourceList{}
+	for _, container := range podMetrics.Containers {
+		addResourceList(podResources, container.Usage)
+		if _, ok := usage.containers[container.Name]; !ok {
+			usage.containers[container.Name] = &containerUsage{}
+		}
+		usage.containers[container.Name].add(container.Usage)
+	}
+	usage.add(podResources)
+}
+
+// setContainerUsage sets the average usage of a pod of the containers, the containers without metrics are skipped.
+func (usage *controllerUsage) setContainerUsage(containerItems []ContainerItem) {
+	for i := range containerItems {
+		container, ok := usage.containers[containerItems[i].Name]
+		if !ok {
+			continue
+		}
+		cpu := resource.NewMilliQuantity(container.cpu.MilliValue()/int64(container.pods), resource.DecimalSI)
+		memory := resource.NewQuantity(container.memory.Value()/int64(container.pods), resource.BinarySI)
+		containerItems[i].Usage = newUsageItem(*cpu, *memory, container.pods, containerItems[i].ResourceItem)
+	}
+}
+
+// setUsage sets the usage of all pods of controllerItem, whose ratios are against the effective pod resources of the
+// pods with metrics, and the average usage of every container.
+func (usage *controllerUsage) setUsage(controllerItem *ControllerItem) {
+	pods := int32(usage.pods)
+	measured := ResourceItem{
+		RequestCPU: multiplyQuantity(controllerItem.EffectivePod.RequestCPU, pods),
+		RequestMem: multiplyQuantity(controllerItem.EffectivePod.RequestMem, pods),
+		LimitCPU:   multiplyQuantity(controllerItem.EffectivePod.LimitCPU, pods),
+		LimitMem:   multiplyQuantity(controllerItem.EffectivePod.LimitMem, pods),
+	}
+	controllerItem.Usage = newUsageItem(usage.cpu, usage.memory, usage.pods, measured)
+	usage.setContainerUsage(controllerItem.InitContainer)
+	usage.setContainerUsage(controllerItem.Container)
+}
+
+// getUsage returns the usage of the running pods of namespace with metrics by the key of their controller in
+// podControllers, which holds the controllerKey by pod namespace/name.
+func getUsage(ctx context.Context, dynamicClient dynamic.Interface, namespace string, podControllers map[string]string, options Options) (map[string]*controllerUsage, error) {
+	result := map[string]*controllerUsage{}
+	err := listPages(ctx, "podmetrics", namespace, options, dynamicClient.Resource(PodMetricsResource).Namespace(namespace).List, func(metricsList *unstructured.UnstructuredList) error {
+		for i := range metricsList.Items {
+			var podMetrics metricsv1beta1.PodMetrics
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(metricsList.Items[i].Object, &podMetrics); err != nil {
+				return err
+			}
+			key, ok := podControllers[podMetrics.Namespace+"/"+podMetrics.Name]
+			if !ok {
+				continue
+			}
+			if _, ok := result[key]; !ok {
+				result[key] = &controllerUsage{containers: map[string]*containerUsage{}}
+			}
+			result[key].addPod(podMetrics)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// addUsage sets the usage of every item in result from the metrics of every namespace, the pods are matched to their
+// controller by podControllers. addErrors returns the first error when the collection should be aborted.
+func addUsage(ctx context.Context, dynamicClient dynamic.Interface, namespaces []string, result []ControllerItem, podControllers map[string]string, options Options, addErrors func(...CollectError) error) error {
+	usages := make([]map[string]*controllerUsage, len(namespaces))
+	tasks := make([]collectTask, 0, len(namespaces))
+	for index, namespace := range namespaces {
+		tasks = append(tasks, collectTask{namespace: namespace, kind: "PodMetrics", collect: func(ctx context.Context, namespace string) ([]ControllerItem, error) {
+			usage, err := getUsage(ctx, dynamicClient, namespace, podControllers, options)
+			usages[index] = usage
+			return nil, err
+		}})
+	}
+	if err := runTasks(ctx, tasks, options.Workers, options.FailOnError); err != nil {
+		return err
+	}
+	// the namespaces are disjoint, their usages are merged to set them in a single pass
+	usage := map[string]*controllerUsage{}
+	for index, task := range tasks {
+		if task.err != nil {
+			if err := addErrors(newCollectError(task.namespace, task.kind, task.err)); err != nil {
+				return err
+			}
+			continue
+		}
+		for key, controllerUsage := range usages[index] {
+			usage[key] = controllerUsage
+		}
+	}
+	for i := range result {
+		item := &result[i]
+		if controllerUsage, ok := usage[controllerKey(item.ControllerType, item.Namespace, item.Controller)]; ok {
+			controllerUsage.setUsage(item)
+		}
+	}
+	return nil
+}
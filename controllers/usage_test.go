@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func testPodMetrics(t *testing.T, name, cpu, memory string) *unstructured.Unstructured {
+	podMetrics := &metricsv1beta1.PodMetrics{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "metrics.k8s.io/v1beta1", Kind: "PodMetrics"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Containers: []metricsv1beta1.ContainerMetrics{{
+			Name:  "app",
+			Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)},
+		}},
+	}
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(podMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: object}
+}
+
+func TestGetControllerItemsMetrics(t *testing.T) {
+	running := v1.PodStatus{Phase: v1.PodRunning}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(2), Template: testTemplate("100m", "128Mi")}},
+		&appsv1.ReplicaSet{ObjectMeta: objectMeta("web-1", controllerRef("Deployment", "web")), Spec: appsv1.ReplicaSetSpec{Replicas: int32Ptr(2), Template: testTemplate("100m", "128Mi")}},
+		&v1.Pod{ObjectMeta: objectMeta("web-1-a", controllerRef("ReplicaSet", "web-1")), Spec: testPodSpec("100m", "128Mi"), Status: running},
+		&v1.Pod{ObjectMeta: objectMeta("web-1-b", controllerRef("ReplicaSet", "web-1")), Spec: testPodSpec("100m", "128Mi"), Status: running},
+		&v1.Pod{ObjectMeta: objectMeta("bare", nil), Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}, Status: running},
+	)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{PodMetricsResource: "PodMetricsList"})
+	for _, podMetrics := range []*unstructured.Unstructured{
+		testPodMetrics(t, "web-1-a", "50m", "64Mi"),
+		testPodMetrics(t, "web-1-b", "150m", "128Mi"),
+		testPodMetrics(t, "bare", "10m", "16Mi"),
+		// the metrics of a deleted pod are skipped
+		testPodMetrics(t, "deleted", "1", "1Gi"),
+	} {
+		if err := dynamicClient.Tracker().Create(PodMetricsResource, podMetrics, "default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, collectErrors, err := GetControllerItems(context.Background(), clientset, dynamicClient, Options{Metrics: true, PageSize: 1})
+	if err != nil || len(collectErrors) > 0 || len(result) != 2 {
+		t.Fatalf("GetControllerItems() = %v, %v, %v", result, collectErrors, err)
+	}
+	items := map[string]ControllerItem{}
+	for _, item := range result {
+		items[item.Controller] = item
+	}
+
+	web := items["web"]
+	if web.Usage == nil || web.Usage.Pods != 2 || web.Usage.CPU.Cmp(resource.MustParse("200m")) != 0 {
+		t.Fatalf("web usage = %+v, want 200m of 2 pods", web.Usage)
+	}
+	// 200m of 2 * 100m requests, 192Mi of 2 * 128Mi
+	if *web.Usage.CPURequestRatio != 1 || *web.Usage.MemoryRequestRatio != 0.75 {
+		t.Errorf("web usage ratios = %v, %v, want 1, 0.75", *web.Usage.CPURequestRatio, *web.Usage.MemoryRequestRatio)
+	}
+	app := web.Container[0].Usage
+	if app == nil || app.CPU.Cmp(resource.MustParse("100m")) != 0 || app.Memory.Cmp(resource.MustParse("96Mi")) != 0 {
+		t.Fatalf("web container usage = %+v, want the average 100m, 96Mi", app)
+	}
+	if *app.CPULimitRatio != 1 || *app.MemoryLimitRatio != 0.75 {
+		t.Errorf("web container limit ratios = %v, %v, want 1, 0.75", *app.CPULimitRatio, *app.MemoryLimitRatio)
+	}
+
+	bare := items["bare"]
+	if bare.Usage == nil || bare.Usage.CPU.Cmp(resource.MustParse("10m")) != 0 {
+		t.Fatalf("bare usage = %+v, want 10m", bare.Usage)
+	}
+	// the ratios are not set without requests and limits
+	if bare.Container[0].Usage.CPURequestRatio != nil || bare.Container[0].Usage.MemoryLimitRatio != nil {
+		t.Errorf("bare container usage = %+v, want no ratios", bare.Container[0].Usage)
+	}
+}
+
+func TestGetControllerItemsMetricsError(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", nil), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(1), Template: testTemplate("100m", "128Mi")}},
+	)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{PodMetricsResource: "PodMetricsList"})
+	dynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewServiceUnavailable("metrics-server is not available")
+	})
+
+	result, collectErrors, err := GetControllerItems(context.Background(), clientset, dynamicClient, Options{Metrics: true})
+	if err != nil || len(result) != 1 {
+		t.Fatalf("GetControllerItems() = %v, %v", result, err)
+	}
+	if len(collectErrors) != 1 || collectErrors[0].Kind != "PodMetrics" || result[0].Usage != nil {
+		t.Errorf("collectErrors = %v, usage = %v, want a PodMetrics error and no usage", collectErrors, result[0].Usage)
+	}
+	if _, _, err := GetControllerItems(context.Background(), clientset, dynamicClient, Options{Metrics: true, FailOnError: true}); err == nil {
+		t.Error("GetControllerItems() returns no error with FailOnError")
+	}
+}
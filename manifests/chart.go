@@ -0,0 +1,98 @@
+package manifests
+
+import (
+	"fmt"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/strvals"
+	"k8s.io/apimachinery/pkg/runtime"
+	"strings"
+)
+
+const notesFileSuffix = "NOTES.txt"
+
+// ChartOptions are the settings a chart is rendered with, the same as helm template.
+type ChartOptions struct {
+	ReleaseName string
+	Namespace   string
+	// ValueFiles and Values are merged in order, like -f/--values and --set of helm
+	ValueFiles []string
+	Values     []string
+}
+
+// mergeValues merges values into base, the nested tables are merged and the other values are replaced.
+func mergeValues(base, values map[string]interface{}) map[string]interface{} {
+	for key, value := range values {
+		if table, ok := value.(map[string]interface{}); ok {
+			if baseTable, ok := base[key].(map[string]interface{}); ok {
+				base[key] = mergeValues(baseTable, table)
+				continue
+			}
+		}
+		base[key] = value
+	}
+	return base
+}
+
+func (options ChartOptions) chartValues() (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, valueFile := range options.ValueFiles {
+		fileValues, err := chartutil.ReadValuesFile(valueFile)
+		if err != nil {
+			return nil, err
+		}
+		result = mergeValues(result, fileValues)
+	}
+	for _, value := range options.Values {
+		if err := strvals.ParseInto(value, result); err != nil {
+			return nil, fmt.Errorf("parse --set %q failed: %v", value, err)
+		}
+	}
+	return result, nil
+}
+
+// RenderChart renders the local chart at chartPath without a cluster and decodes the rendered manifests. Hooks, e.g.
+// tests and pre-install jobs, only run temporarily and are skipped.
+func RenderChart(chartPath string, options ChartOptions) ([]runtime.Object, error) {
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("load chart %s failed: %v", chartPath, err)
+	}
+	chartValues, err := options.chartValues()
+	if err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessDependencies(chart, chartValues); err != nil {
+		return nil, err
+	}
+	releaseOptions := chartutil.ReleaseOptions{Name: options.ReleaseName, Namespace: options.Namespace, Revision: 1, IsInstall: true}
+	renderValues, err := chartutil.ToRenderValues(chart, chartValues, releaseOptions, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := engine.Render(chart, renderValues)
+	if err != nil {
+		return nil, fmt.Errorf("render chart %s failed: %v", chartPath, err)
+	}
+	for name := range rendered {
+		// NOTES.txt is neither a hook nor a resource
+		if strings.HasSuffix(name, notesFileSuffix) {
+			delete(rendered, name)
+		}
+	}
+	_, manifests, err := releaseutil.SortManifests(rendered, chartutil.DefaultCapabilities.APIVersions, releaseutil.InstallOrder)
+	if err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	for _, manifest := range manifests {
+		manifestObjects, err := Decode([]byte(manifest.Content), options.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s failed: %v", manifest.Name, err)
+		}
+		objects = append(objects, manifestObjects...)
+	}
+	return objects, nil
+}
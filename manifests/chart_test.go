@@ -0,0 +1,90 @@
+package manifests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+var testChart = map[string]string{
+	"Chart.yaml": "apiVersion: v2\nname: demo\nversion: 0.1.0\n",
+	"values.yaml": `replicaCount: 1
+resources:
+  requests:
+    cpu: 100m
+    memory: 128Mi
+`,
+	"templates/NOTES.txt":    "{{ .Release.Name }} installed\n",
+	"templates/_helpers.tpl": `{{- define "demo.name" -}}{{ .Release.Name }}-web{{- end }}`,
+	"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "demo.name" . }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  template:
+    spec:
+      containers:
+      - name: web
+        resources: {{- toYaml .Values.resources | nindent 10 }}
+`,
+	"templates/tests/test.yaml": `apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ .Release.Name }}-test
+  annotations:
+    helm.sh/hook: test
+spec:
+  containers:
+  - name: test
+`,
+}
+
+func TestRenderChart(t *testing.T) {
+	dir := t.TempDir()
+	chartPath := filepath.Join(dir, "demo")
+	for name, content := range testChart {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	valuesFile := filepath.Join(dir, "prod.yaml")
+	if err := os.WriteFile(valuesFile, []byte("replicaCount: 3\nresources:\n  limits:\n    cpu: 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := RenderChart(chartPath, ChartOptions{
+		ReleaseName: "shop",
+		Namespace:   "prod",
+		ValueFiles:  []string{valuesFile},
+		Values:      []string{"resources.requests.cpu=1"},
+	})
+	if err != nil {
+		t.Fatalf("RenderChart() error = %v", err)
+	}
+	// the test hook is skipped
+	if len(objects) != 1 {
+		t.Fatalf("RenderChart() returns %d objects, want 1", len(objects))
+	}
+	deployment, ok := objects[0].(*appsv1.Deployment)
+	if !ok {
+		t.Fatalf("objects[0] = %#v, want a Deployment", objects[0])
+	}
+	if deployment.Namespace != "prod" || deployment.Name != "shop-web" || *deployment.Spec.Replicas != 3 {
+		t.Errorf("deployment = %s/%s with %d replicas, want prod/shop-web with 3", deployment.Namespace, deployment.Name, *deployment.Spec.Replicas)
+	}
+	resources := deployment.Spec.Template.Spec.Containers[0].Resources
+	if resources.Requests.Cpu().String() != "1" || resources.Requests.Memory().String() != "128Mi" || resources.Limits.Cpu().String() != "2" {
+		t.Errorf("resources = %v, want merged values", resources)
+	}
+
+	if _, err := RenderChart(filepath.Join(dir, "missing"), ChartOptions{ReleaseName: "shop", Namespace: "prod"}); err == nil {
+		t.Errorf("RenderChart() of a missing chart returns no error")
+	}
+}
@@ -0,0 +1,50 @@
+package manifests
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// BuildKustomization builds the kustomization in dir in-process, the same as kustomize build, and decodes the result.
+func BuildKustomization(dir string) ([]runtime.Object, error) {
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := kustomizer.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("build kustomization %s failed: %v", dir, err)
+	}
+	data, err := resources.AsYaml()
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data, DefaultNamespace)
+}
+
+// Source is a set of manifests collected separately from the other sources, Name tags its controllers.
+type Source struct {
+	Name    string
+	Objects []runtime.Object
+}
+
+// LoadSources returns a source of the manifests of files and dirs, named "", unless there is none, followed by a
+// source for every kustomization of kustomizeDirs named by its directory, so overlays of the same application can be
+// collected and compared side by side.
+func LoadSources(files, dirs, kustomizeDirs []string) ([]Source, error) {
+	var sources []Source
+	if len(files) > 0 || len(dirs) > 0 {
+		objects, err := Load(files, dirs)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, Source{Objects: objects})
+	}
+	for _, dir := range kustomizeDirs {
+		objects, err := BuildKustomization(dir)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, Source{Name: dir, Objects: objects})
+	}
+	return sources, nil
+}
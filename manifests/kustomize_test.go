@@ -0,0 +1,102 @@
+package manifests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+var testKustomization = map[string]string{
+	"base/kustomization.yaml": "resources:\n- deployment.yaml\n",
+	"base/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: web
+        resources:
+          requests:
+            cpu: 100m
+`,
+	"overlays/prod/kustomization.yaml": `namespace: prod
+resources:
+- ../../base
+replicas:
+- name: web
+  count: 5
+patches:
+- target:
+    kind: Deployment
+    name: web
+  patch: |-
+    - op: replace
+      path: /spec/template/spec/containers/0/resources/requests/cpu
+      value: "1"
+`,
+	// the same deployment in the same namespace as the prod overlay
+	"overlays/staging/kustomization.yaml": `namespace: prod
+resources:
+- ../../base
+replicas:
+- name: web
+  count: 2
+`,
+}
+
+func TestLoadSources(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range testKustomization {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kustomizeDirs := []string{filepath.Join(dir, "base"), filepath.Join(dir, "overlays", "prod"), filepath.Join(dir, "overlays", "staging")}
+	sources, err := LoadSources(nil, nil, kustomizeDirs)
+	if err != nil {
+		t.Fatalf("LoadSources() error = %v", err)
+	}
+	if len(sources) != 3 {
+		t.Fatalf("LoadSources() returns %d sources, want 3", len(sources))
+	}
+	tests := []struct {
+		namespace string
+		replicas  int32
+		cpu       string
+	}{
+		{DefaultNamespace, 1, "100m"},
+		{"prod", 5, "1"},
+		{"prod", 2, "100m"},
+	}
+	for i, test := range tests {
+		if sources[i].Name != kustomizeDirs[i] || len(sources[i].Objects) != 1 {
+			t.Fatalf("sources[%d] = %s with %d objects, want %s with 1 object", i, sources[i].Name, len(sources[i].Objects), kustomizeDirs[i])
+		}
+		deployment, ok := sources[i].Objects[0].(*appsv1.Deployment)
+		if !ok {
+			t.Fatalf("sources[%d] = %#v, want a Deployment", i, sources[i].Objects[0])
+		}
+		cpu := deployment.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()
+		if deployment.Namespace != test.namespace || *deployment.Spec.Replicas != test.replicas || cpu != test.cpu {
+			t.Errorf("sources[%d] = %s with %d replicas and %s cpu, want %s with %d replicas and %s cpu", i,
+				deployment.Namespace, *deployment.Spec.Replicas, cpu, test.namespace, test.replicas, test.cpu)
+		}
+		// every source is served by its own clients
+		if _, _, err := NewClients(sources[i].Objects, nil); err != nil {
+			t.Errorf("NewClients() of sources[%d] error = %v", i, err)
+		}
+	}
+
+	if _, err := LoadSources(nil, nil, []string{filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("LoadSources() of a missing directory returns no error")
+	}
+}
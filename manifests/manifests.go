@@ -0,0 +1,297 @@
+package manifests
+
+import (
+	"bufio"
+	"bytes"
+	"example.com/dev/k8s/controllers"
+	"fmt"
+	"io"
+	"io/fs"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultNamespace is set on the namespaced objects which have no namespace, the same as kubectl apply does.
+const DefaultNamespace = metav1.NamespaceDefault
+
+// clusterScopedKinds are the built-in kinds which must not get the default namespace, the scope of the custom kinds is
+// read from their CustomResourceDefinition by NewClients.
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"StorageClass":                   true,
+	"CSIDriver":                      true,
+	"CSINode":                        true,
+	"VolumeAttachment":               true,
+	"RuntimeClass":                   true,
+	"PriorityClass":                  true,
+	"IngressClass":                   true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"APIService":                     true,
+	"MutatingWebhookConfiguration":   true,
+	"ValidatingWebhookConfiguration": true,
+}
+
+var manifestExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// Load decodes the manifests of files and of the .yaml, .yml and .json files found recursively in dirs.
+func Load(files, dirs []string) ([]runtime.Object, error) {
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && manifestExtensions[strings.ToLower(filepath.Ext(path))] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var objects []runtime.Object
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileObjects, err := Decode(data, DefaultNamespace)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s failed: %v", file, err)
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return objects, nil
+}
+
+// LoadNodes returns the nodes of the manifests of files, e.g. saved by kubectl get nodes -o yaml, the other kinds are
+// skipped.
+func LoadNodes(files []string) ([]v1.Node, error) {
+	objects, err := Load(files, nil)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []v1.Node
+	for _, object := range objects {
+		if node, ok := object.(*v1.Node); ok {
+			nodes = append(nodes, *node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no node found in %s", strings.Join(files, ", "))
+	}
+	return nodes, nil
+}
+
+// Decode decodes multi-document YAML or JSON, the items of List kinds are returned one by one. Kinds known by the
+// client-go scheme are returned as typed objects, other kinds as *unstructured.Unstructured. The namespaced objects
+// which have no namespace are put in namespace.
+func Decode(data []byte, namespace string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			return objects, nil
+		} else if err != nil {
+			return nil, err
+		}
+		jsonData, err := utilyaml.ToJSON(document)
+		if err != nil {
+			return nil, err
+		}
+		if jsonData = bytes.TrimSpace(jsonData); len(jsonData) == 0 || bytes.Equal(jsonData, []byte("null")) {
+			// empty documents and documents with comments only
+			continue
+		}
+		object, err := runtime.Decode(unstructured.UnstructuredJSONScheme, jsonData)
+		if err != nil {
+			return nil, err
+		}
+		var items []unstructured.Unstructured
+		if list, ok := object.(*unstructured.UnstructuredList); ok {
+			items = list.Items
+		} else {
+			items = []unstructured.Unstructured{*object.(*unstructured.Unstructured)}
+		}
+		for i := range items {
+			typedObject, err := convert(&items[i], namespace)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, typedObject)
+		}
+	}
+}
+
+func convert(object *unstructured.Unstructured, namespace string) (runtime.Object, error) {
+	gvk := object.GroupVersionKind()
+	if object.GetNamespace() == "" && !clusterScopedKinds[gvk.Kind] {
+		object.SetNamespace(namespace)
+	}
+	if !scheme.Scheme.Recognizes(gvk) {
+		return object, nil
+	}
+	typedObject, err := scheme.Scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), typedObject); err != nil {
+		return nil, fmt.Errorf("convert %s %s/%s failed: %v", gvk.Kind, object.GetNamespace(), object.GetName(), err)
+	}
+	typedObject.GetObjectKind().SetGroupVersionKind(gvk)
+	return typedObject, nil
+}
+
+// objectKind returns the kind of object, typed objects decoded without apiVersion and kind are looked up in the
+// client-go scheme.
+func objectKind(object runtime.Object) schema.GroupVersionKind {
+	if gvk := object.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		return gvk
+	}
+	if gvks, _, err := scheme.Scheme.ObjectKinds(object); err == nil && len(gvks) > 0 {
+		return gvks[0]
+	}
+	return schema.GroupVersionKind{}
+}
+
+// newRESTMapper maps the kinds of the client-go scheme, which are cluster scoped when they are in
+// clusterScopedKinds, and the kinds defined by the CustomResourceDefinitions of objects, by their plural and scope.
+func newRESTMapper(objects []runtime.Object) meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for gvk := range scheme.Scheme.AllKnownTypes() {
+		if clusterScopedKinds[gvk.Kind] {
+			mapper.Add(gvk, meta.RESTScopeRoot)
+		} else {
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+		}
+	}
+	for _, object := range objects {
+		if objectKind(object).Kind != "CustomResourceDefinition" {
+			continue
+		}
+		// the CustomResourceDefinitions are typed when their scheme is registered, e.g. by helm
+		crd, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+		if err != nil {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(crd, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd, "spec", "names", "kind")
+		plural, _, _ := unstructured.NestedString(crd, "spec", "names", "plural")
+		singular, _, _ := unstructured.NestedString(crd, "spec", "names", "singular")
+		if kind == "" || plural == "" {
+			continue
+		}
+		if singular == "" {
+			singular = strings.ToLower(kind)
+		}
+		scope := meta.RESTScopeNamespace
+		if scopeName, _, _ := unstructured.NestedString(crd, "spec", "scope"); scopeName == "Cluster" {
+			scope = meta.RESTScopeRoot
+		}
+		versions, _, _ := unstructured.NestedSlice(crd, "spec", "versions")
+		for _, version := range versions {
+			versionMap, ok := version.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(versionMap, "name")
+			mapper.AddSpecific(schema.GroupVersionKind{Group: group, Version: name, Kind: kind},
+				schema.GroupVersionResource{Group: group, Version: name, Resource: plural},
+				schema.GroupVersionResource{Group: group, Version: name, Resource: singular}, scope)
+		}
+	}
+	return mapper
+}
+
+// dedupe returns objects without duplicates, an object defined more than once is replaced by its last definition,
+// the same as kubectl apply does, with a warning.
+func dedupe(objects []runtime.Object) []runtime.Object {
+	indexes := map[string]int{}
+	result := make([]runtime.Object, 0, len(objects))
+	for _, object := range objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			result = append(result, object)
+			continue
+		}
+		gvk := objectKind(object)
+		key := gvk.GroupKind().String() + "/" + accessor.GetNamespace() + "/" + accessor.GetName()
+		if index, ok := indexes[key]; ok {
+			klog.Warningf("%s %s/%s is defined more than once, the last definition is used", gvk.Kind, accessor.GetNamespace(), accessor.GetName())
+			result[index] = object
+			continue
+		}
+		indexes[key] = len(result)
+		result = append(result, object)
+	}
+	return result
+}
+
+// NewClients returns in-memory clients serving objects. The unstructured objects are served by the dynamic client
+// under the resource of their CustomResourceDefinition in objects, or guessed from their kind without it, e.g.
+// CloneSet as clonesets, and their namespace is removed when they are cluster scoped. Duplicated objects are served
+// once by their last definition.
+func NewClients(objects []runtime.Object, customResources []controllers.CustomResource) (kubernetes.Interface, dynamic.Interface, error) {
+	mapper := newRESTMapper(objects)
+	// customResourceOf returns the resource of a custom object and removes its namespace when it is cluster scoped
+	customResourceOf := func(customObject *unstructured.Unstructured) schema.GroupVersionResource {
+		gvk := customObject.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+			return gvr
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+			customObject.SetNamespace("")
+		}
+		return mapping.Resource
+	}
+	for _, object := range objects {
+		if customObject, ok := object.(*unstructured.Unstructured); ok {
+			customResourceOf(customObject)
+		}
+	}
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, customResource := range customResources {
+		listKinds[customResource.GroupVersionResource()] = "List"
+	}
+	var customObjects []*unstructured.Unstructured
+	clientset := fake.NewSimpleClientset()
+	for _, object := range dedupe(objects) {
+		if customObject, ok := object.(*unstructured.Unstructured); ok {
+			listKinds[customResourceOf(customObject)] = customObject.GetKind() + "List"
+			customObjects = append(customObjects, customObject)
+		} else if err := clientset.Tracker().Add(object); runtime.IsNotRegisteredError(err) {
+			// the typed kinds of other schemes, e.g. CustomResourceDefinition, are not collected
+			klog.Infof("%s is not served offline: %v", objectKind(object).Kind, err)
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	for _, customObject := range customObjects {
+		if err := dynamicClient.Tracker().Create(customResourceOf(customObject), customObject, customObject.GetNamespace()); err != nil {
+			return nil, nil, fmt.Errorf("add %s %s/%s failed: %v", customObject.GetKind(), customObject.GetNamespace(), customObject.GetName(), err)
+		}
+	}
+	return clientset, dynamicClient, nil
+}
@@ -0,0 +1,221 @@
+package manifests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/dev/k8s/controllers"
+	appsv1 "k8s.io/api/apps/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testManifests = `# only a comment
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 500m
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: StatefulSet
+  metadata:
+    name: db
+    namespace: data
+  spec:
+    replicas: 3
+    template:
+      spec:
+        containers:
+        - name: db
+- apiVersion: storage.k8s.io/v1
+  kind: StorageClass
+  metadata:
+    name: standard
+  provisioner: example.com/disk
+`
+
+const testCustomManifest = `{"apiVersion": "apps.kruise.io/v1alpha1", "kind": "CloneSet", "metadata": {"name": "cs"},
+"spec": {"replicas": 2, "template": {"spec": {"containers": [{"name": "c"}]}}}}`
+
+func TestDecode(t *testing.T) {
+	objects, err := Decode([]byte(testManifests), DefaultNamespace)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(objects) != 3 {
+		t.Fatalf("Decode() returns %d objects, want 3", len(objects))
+	}
+	if deployment, ok := objects[0].(*appsv1.Deployment); !ok || deployment.Namespace != DefaultNamespace {
+		t.Errorf("objects[0] = %#v, want a Deployment in namespace %q", objects[0], DefaultNamespace)
+	}
+	if statefulSet, ok := objects[1].(*appsv1.StatefulSet); !ok || statefulSet.Namespace != "data" || *statefulSet.Spec.Replicas != 3 {
+		t.Errorf("objects[1] = %#v, want the StatefulSet data/db", objects[1])
+	}
+	if storageClass, ok := objects[2].(*storagev1.StorageClass); !ok || storageClass.Namespace != "" {
+		t.Errorf("objects[2] = %#v, want a cluster scoped StorageClass", objects[2])
+	}
+
+	objects, err = Decode([]byte(testCustomManifest), DefaultNamespace)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("Decode() returns %d objects, want 1", len(objects))
+	}
+	if cloneSet, ok := objects[0].(*unstructured.Unstructured); !ok || cloneSet.GetKind() != "CloneSet" || cloneSet.GetNamespace() != DefaultNamespace {
+		t.Errorf("objects[0] = %#v, want an unstructured CloneSet", objects[0])
+	}
+
+	if _, err := Decode([]byte("metadata:\n  name: web\n"), DefaultNamespace); err == nil {
+		t.Errorf("Decode() of a manifest without kind returns no error")
+	}
+}
+
+func TestLoadAndCollect(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "custom"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"workloads.yaml":    testManifests,
+		"custom/clone.json": testCustomManifest,
+		"custom/README.md":  "not a manifest",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	objects, err := Load(nil, []string{dir})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	customResources := []controllers.CustomResource{{Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets"}}
+	clientset, dynamicClient, err := NewClients(objects, customResources)
+	if err != nil {
+		t.Fatalf("NewClients() error = %v", err)
+	}
+	if _, err := clientset.StorageV1().StorageClasses().Get(context.Background(), "standard", metav1.GetOptions{}); err != nil {
+		t.Errorf("get storageclass failed: %v", err)
+	}
+
+	result, collectErrors, err := controllers.GetControllerItems(context.Background(), clientset, dynamicClient,
+		controllers.Options{CustomResources: customResources})
+	if err != nil || len(collectErrors) > 0 {
+		t.Fatalf("GetControllerItems() errors = %v, %v", collectErrors, err)
+	}
+	want := map[string]int32{"default/Deployment/web": 1, "data/Statefulset/db": 3, "default/CloneSet/cs": 2}
+	if len(result) != len(want) {
+		t.Errorf("GetControllerItems() returns %d items, want %d", len(result), len(want))
+	}
+	for _, item := range result {
+		key := item.Namespace + "/" + item.ControllerType + "/" + item.Controller
+		if replicas, ok := want[key]; !ok || replicas != item.Replicas {
+			t.Errorf("unexpected item %s with %d replicas", key, item.Replicas)
+		}
+	}
+}
+
+const testDuplicateManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gateways.example.com
+spec:
+  group: example.com
+  scope: Cluster
+  names:
+    kind: Gateway
+    plural: gatewayclasses
+  versions:
+  - name: v1
+---
+apiVersion: example.com/v1
+kind: Gateway
+metadata:
+  name: edge
+`
+
+func TestNewClientsDuplicatesAndScope(t *testing.T) {
+	objects, err := Decode([]byte(testDuplicateManifests), DefaultNamespace)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	// the same objects defined again, the last definition wins
+	duplicates, err := Decode([]byte(strings.Replace(testDuplicateManifests, "replicas: 1", "replicas: 4", 1)), DefaultNamespace)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	clientset, dynamicClient, err := NewClients(append(objects, duplicates...), nil)
+	if err != nil {
+		t.Fatalf("NewClients() error = %v", err)
+	}
+	deployment, err := clientset.AppsV1().Deployments(DefaultNamespace).Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil || *deployment.Spec.Replicas != 4 {
+		t.Errorf("get deployment = %v, %v, want the last definition with 4 replicas", deployment, err)
+	}
+	// the gateway is cluster scoped and served under the plural of its CustomResourceDefinition
+	gateways := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gatewayclasses"}
+	if _, err := dynamicClient.Resource(gateways).Get(context.Background(), "edge", metav1.GetOptions{}); err != nil {
+		t.Errorf("get cluster scoped gateway failed: %v", err)
+	}
+}
+
+const testNodes = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: worker-1
+    labels:
+      zone: a
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: worker-2
+`
+
+func TestLoadNodes(t *testing.T) {
+	dir := t.TempDir()
+	nodesFile, workloadsFile := filepath.Join(dir, "nodes.yaml"), filepath.Join(dir, "workloads.yaml")
+	if err := os.WriteFile(nodesFile, []byte(testNodes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(workloadsFile, []byte(testManifests), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := LoadNodes([]string{nodesFile, workloadsFile})
+	if err != nil {
+		t.Fatalf("LoadNodes() error = %v", err)
+	}
+	// the nodes are cluster scoped and the other kinds are skipped
+	if len(nodes) != 2 || nodes[0].Name != "worker-1" || nodes[0].Namespace != "" || nodes[0].Labels["zone"] != "a" {
+		t.Errorf("LoadNodes() = %+v, want worker-1 and worker-2", nodes)
+	}
+	if _, err := LoadNodes([]string{workloadsFile}); err == nil {
+		t.Errorf("LoadNodes() error = nil without nodes")
+	}
+}
@@ -0,0 +1,97 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	queryPath     = "/api/v1/query"
+	statusSuccess = "success"
+	resultVector  = "vector"
+)
+
+// Client queries the HTTP API of Prometheus, or of any server compatible with it, e.g. Thanos or Mimir.
+type Client struct {
+	URL string
+	// BearerToken is sent in the Authorization header when it is set
+	BearerToken string
+	HTTPClient  *http.Client
+}
+
+// Sample is an element of an instant vector.
+type Sample struct {
+	Metric map[string]string
+	Value  float64
+}
+
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			// Value is [<unix time>, "<value>"]
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+func NewClient(url, bearerToken string) *Client {
+	return &Client{URL: strings.TrimSuffix(url, "/"), BearerToken: bearerToken, HTTPClient: http.DefaultClient}
+}
+
+// Query evaluates query at the current time, which must return an instant vector. The NaN samples are skipped.
+func (client *Client) Query(ctx context.Context, query string) ([]Sample, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, client.URL+queryPath, strings.NewReader(url.Values{"query": {query}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if client.BearerToken != "" {
+		request.Header.Set("Authorization", "Bearer "+client.BearerToken)
+	}
+	response, err := client.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result queryResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("query %q failed with status %s: %s", query, response.Status, body)
+	}
+	if result.Status != statusSuccess {
+		return nil, fmt.Errorf("query %q failed: %s: %s", query, result.ErrorType, result.Error)
+	}
+	if result.Data.ResultType != resultVector {
+		return nil, fmt.Errorf("query %q returns a %s, want a %s", query, result.Data.ResultType, resultVector)
+	}
+	samples := make([]Sample, 0, len(result.Data.Result))
+	for _, series := range result.Data.Result {
+		if len(series.Value) != 2 {
+			return nil, fmt.Errorf("query %q returns the invalid value %v", query, series.Value)
+		}
+		value, err := strconv.ParseFloat(fmt.Sprint(series.Value[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("query %q returns the invalid value %v: %v", query, series.Value[1], err)
+		}
+		if math.IsNaN(value) {
+			continue
+		}
+		samples = append(samples, Sample{Metric: series.Metric, Value: value})
+	}
+	return samples, nil
+}
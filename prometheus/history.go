@@ -0,0 +1,202 @@
+package prometheus
+
+import (
+	"context"
+	"example.com/dev/k8s/controllers"
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"regexp"
+	"sort"
+)
+
+const (
+	DefaultWindow     = "7d"
+	DefaultResolution = "5m"
+	cpuMetric         = "container_cpu_usage_seconds_total"
+	memoryMetric      = "container_memory_working_set_bytes"
+	// containerMatchers skip the cgroups of the pods and of the pause containers
+	containerMatchers = `container!="",container!="POD"`
+)
+
+// durationPattern is the duration format of Prometheus, e.g. 5m, 1h30m or 7d.
+var durationPattern = regexp.MustCompile(`^([0-9]+(ms|s|m|h|d|w|y))+$`)
+
+// HistoryOptions are the settings of the usage history queries, which are read from the prometheus key of the config.
+type HistoryOptions struct {
+	URL         string `mapstructure:"url"`
+	BearerToken string `mapstructure:"bearerToken"`
+	// Window is the range of the history, default DefaultWindow
+	Window string `mapstructure:"window"`
+	// Resolution is the range of the cpu rate and the step of its subquery, default DefaultResolution
+	Resolution string `mapstructure:"resolution"`
+	// Selector is added to the label matchers of the container metrics, e.g. cluster="prod"
+	Selector string `mapstructure:"selector"`
+}
+
+// Validate checks the options and sets the defaults.
+func (options *HistoryOptions) Validate() error {
+	if options.URL == "" {
+		return fmt.Errorf("no prometheus url, set prometheus.url in the config file or --prometheus-url")
+	}
+	if options.Window == "" {
+		options.Window = DefaultWindow
+	}
+	if options.Resolution == "" {
+		options.Resolution = DefaultResolution
+	}
+	for _, duration := range []string{options.Window, options.Resolution} {
+		if !durationPattern.MatchString(duration) {
+			return fmt.Errorf("invalid prometheus duration %q, e.g. 5m, 12h or 7d", duration)
+		}
+	}
+	return nil
+}
+
+// historyQuery is a query of a quantile of the usage of every container, and the field of the history it fills.
+type historyQuery struct {
+	query    string
+	quantile func(history *controllers.UsageHistoryItem) *resource.Quantity
+	// milli is set for cpu, whose samples are in cores
+	milli bool
+}
+
+// historyQueries returns the queries of namespace. Both the cpu and the memory are sampled at the resolution by a
+// subquery, and every namespace is queried separately, so a query stays under the max samples of Prometheus.
+func historyQueries(options HistoryOptions, namespace string) []historyQuery {
+	matchers := containerMatchers + `,namespace="` + namespace + `"`
+	if options.Selector != "" {
+		matchers += "," + options.Selector
+	}
+	cpu := fmt.Sprintf("rate(%s{%s}[%s])[%s:%s]", cpuMetric, matchers, options.Resolution, options.Window, options.Resolution)
+	memory := fmt.Sprintf("%s{%s}[%s:%s]", memoryMetric, matchers, options.Window, options.Resolution)
+	byContainer := func(expression string) string {
+		return "max by (namespace, pod, container) (" + expression + ")"
+	}
+	return []historyQuery{
+		{byContainer("quantile_over_time(0.5, " + cpu + ")"), func(history *controllers.UsageHistoryItem) *resource.Quantity { return &history.CPU.P50 }, true},
+		{byContainer("quantile_over_time(0.95, " + cpu + ")"), func(history *controllers.UsageHistoryItem) *resource.Quantity { return &history.CPU.P95 }, true},
+		{byContainer("max_over_time(" + cpu + ")"), func(history *controllers.UsageHistoryItem) *resource.Quantity { return &history.CPU.Max }, true},
+		{byContainer("quantile_over_time(0.5, " + memory + ")"), func(history *controllers.UsageHistoryItem) *resource.Quantity { return &history.Memory.P50 }, false},
+		{byContainer("quantile_over_time(0.95, " + memory + ")"), func(history *controllers.UsageHistoryItem) *resource.Quantity { return &history.Memory.P95 }, false},
+		{byContainer("max_over_time(" + memory + ")"), func(history *controllers.UsageHistoryItem) *resource.Quantity { return &history.Memory.Max }, false},
+	}
+}
+
+// podNamePattern returns the pattern of the names of the pods created by a controller, custom resources are expected
+// to create their pods directly or through replicasets.
+func podNamePattern(controllerType, name string) *regexp.Regexp {
+	name = regexp.QuoteMeta(name)
+	if controllerType == controllers.TypePod {
+		return regexp.MustCompile("^" + name + "$")
+	} else if controllerType == controllers.TypeDeployment || controllerType == controllers.TypeCronJob {
+		// <deployment>-<replicaset hash>-<suffix>, <cronjob>-<schedule time>-<suffix>
+		return regexp.MustCompile("^" + name + "-[a-z0-9]+-[a-z0-9]+$")
+	} else if controllerType == controllers.TypeStatefulSet {
+		return regexp.MustCompile("^" + name + "-[0-9]+$")
+	} else if controllerType == controllers.TypeReplicaSet || controllerType == controllers.TypeDaemonSet || controllerType == controllers.TypeJob {
+		return regexp.MustCompile("^" + name + "-[a-z0-9]+$")
+	}
+	return regexp.MustCompile("^" + name + "(-[a-z0-9]+){1,2}$")
+}
+
+type podController struct {
+	index   int
+	pattern *regexp.Regexp
+}
+
+// podResolver finds the controller of a pod by the pod names the controllers generate.
+type podResolver struct {
+	// controllers holds the controllers by namespace, the longest names first
+	controllers map[string][]podController
+	items       []controllers.ControllerItem
+}
+
+func newPodResolver(items []controllers.ControllerItem) *podResolver {
+	resolver := &podResolver{controllers: map[string][]podController{}, items: items}
+	for index, item := range items {
+		resolver.controllers[item.Namespace] = append(resolver.controllers[item.Namespace], podController{index, podNamePattern(item.ControllerType, item.Controller)})
+	}
+	for _, podControllers := range resolver.controllers {
+		sort.SliceStable(podControllers, func(i, j int) bool {
+			return len(items[podControllers[i].index].Controller) > len(items[podControllers[j].index].Controller)
+		})
+	}
+	return resolver
+}
+
+// controllerIndex returns the index of the controller of pod, the longest name wins when several controllers match,
+// -1 if there is none.
+func (resolver *podResolver) controllerIndex(namespace, pod string) int {
+	for _, controller := range resolver.controllers[namespace] {
+		if controller.pattern.MatchString(pod) {
+			return controller.index
+		}
+	}
+	return -1
+}
+
+// findContainer returns the container named name of the controller at index, nil if there is none.
+func (resolver *podResolver) findContainer(index int, name string) *controllers.ContainerItem {
+	item := &resolver.items[index]
+	for _, containers := range [][]controllers.ContainerItem{item.InitContainer, item.Container} {
+		for i := range containers {
+			if containers[i].Name == name {
+				return &containers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// AddUsageHistory sets the p50, p95 and max usage over the window of every container of items which has metrics in
+// Prometheus. The pods are matched to the controllers by name, and the quantiles of the pods of a controller are
+// combined by their maximum.
+func AddUsageHistory(ctx context.Context, client *Client, items []controllers.ControllerItem, options HistoryOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	namespaceSet := map[string]bool{}
+	var namespaces []string
+	for _, item := range items {
+		if !namespaceSet[item.Namespace] {
+			namespaceSet[item.Namespace] = true
+			namespaces = append(namespaces, item.Namespace)
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil
+	}
+	sort.Strings(namespaces)
+	resolver := newPodResolver(items)
+	for _, namespace := range namespaces {
+		for _, query := range historyQueries(options, namespace) {
+			samples, err := client.Query(ctx, query.query)
+			if err != nil {
+				return err
+			}
+			for _, sample := range samples {
+				index := resolver.controllerIndex(sample.Metric["namespace"], sample.Metric["pod"])
+				if index < 0 {
+					continue
+				}
+				container := resolver.findContainer(index, sample.Metric["container"])
+				if container == nil {
+					continue
+				}
+				if container.History == nil {
+					container.History = &controllers.UsageHistoryItem{Window: options.Window}
+				}
+				var value *resource.Quantity
+				if query.milli {
+					value = resource.NewMilliQuantity(int64(sample.Value*1000+0.5), resource.DecimalSI)
+				} else {
+					value = resource.NewQuantity(int64(sample.Value+0.5), resource.BinarySI)
+				}
+				if quantile := query.quantile(container.History); value.Cmp(*quantile) > 0 {
+					*quantile = *value
+				}
+			}
+		}
+	}
+	return nil
+}
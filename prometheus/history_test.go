@@ -0,0 +1,147 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"example.com/dev/k8s/controllers"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testTemplate(container string) v1.PodTemplateSpec {
+	return v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: container}}}}
+}
+
+type testSeries struct {
+	pod, container string
+	// values are the cpu cores or memory bytes of p50, p95 and max
+	cpu, memory [3]float64
+}
+
+// newTestServer serves the history queries of series, it checks the queries are sent with the bearer token.
+func newTestServer(t *testing.T, series []testSeries) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != queryPath || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("request %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		query := r.FormValue("query")
+		if strings.Contains(query, "invalid") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"status": "error", "errorType": "bad_data", "error": "parse error"})
+			return
+		}
+		if !strings.Contains(query, `cluster="prod"`) || !strings.Contains(query, "[7d:5m]") {
+			t.Errorf("query %q does not select the selector or is not a subquery", query)
+		}
+		// every namespace is queried separately
+		if strings.Contains(query, `namespace="shop"`) {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status": "success",
+				"data":   map[string]interface{}{"resultType": "vector", "result": []interface{}{}},
+			})
+			return
+		} else if !strings.Contains(query, `namespace="default"`) {
+			t.Errorf("query %q does not select a namespace", query)
+		}
+		quantile := 2
+		if strings.Contains(query, "quantile_over_time(0.5,") {
+			quantile = 0
+		} else if strings.Contains(query, "quantile_over_time(0.95,") {
+			quantile = 1
+		}
+		var result []map[string]interface{}
+		for _, item := range series {
+			value := item.memory[quantile]
+			if strings.Contains(query, cpuMetric) {
+				value = item.cpu[quantile]
+			}
+			result = append(result, map[string]interface{}{
+				"metric": map[string]string{"namespace": "default", "pod": item.pod, "container": item.container},
+				"value":  []interface{}{1700000000.0, strconv.FormatFloat(value, 'f', -1, 64)},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   map[string]interface{}{"resultType": "vector", "result": result},
+		})
+	}))
+}
+
+func TestAddUsageHistory(t *testing.T) {
+	server := newTestServer(t, []testSeries{
+		{"web-5d4f8-abcde", "app", [3]float64{0.1, 0.2, 0.4}, [3]float64{100 << 20, 200 << 20, 300 << 20}},
+		// the quantiles of the pods are combined by their maximum
+		{"web-5d4f8-fghij", "app", [3]float64{0.15, 0.18, 0.5}, [3]float64{50 << 20, 250 << 20, 260 << 20}},
+		{"web-api-7c9-xyz12", "app", [3]float64{1, 1, 1}, [3]float64{1 << 30, 1 << 30, 1 << 30}},
+		{"db-0", "postgres", [3]float64{0.5, 0.75, 1}, [3]float64{1 << 30, 1 << 30, 2 << 30}},
+		// unknown pods and containers are skipped
+		{"db-0", "exporter", [3]float64{1, 1, 1}, [3]float64{1, 1, 1}},
+		{"unknown", "app", [3]float64{1, 1, 1}, [3]float64{1, 1, 1}},
+		// the pods of other controllers are not credited to the statefulset and the daemonset
+		{"db-restore-x7k2p", "postgres", [3]float64{4, 4, 4}, [3]float64{8 << 30, 8 << 30, 8 << 30}},
+		{"agent-x7k2p", "agent", [3]float64{0.01, 0.02, 0.05}, [3]float64{10 << 20, 20 << 20, 30 << 20}},
+		{"agent-canary-5d4f8-abcde", "agent", [3]float64{4, 4, 4}, [3]float64{8 << 30, 8 << 30, 8 << 30}},
+	})
+	defer server.Close()
+
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}, Spec: appsv1.DeploymentSpec{Template: testTemplate("app")}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-api"}, Spec: appsv1.DeploymentSpec{Template: testTemplate("app")}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}, Spec: appsv1.StatefulSetSpec{Template: testTemplate("postgres")}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "agent"}, Spec: appsv1.DaemonSetSpec{Template: testTemplate("agent")}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "cart"}, Spec: appsv1.DeploymentSpec{Template: testTemplate("app")}},
+	)
+	items, collectErrors, err := controllers.GetControllerItems(context.Background(), clientset, nil, controllers.Options{})
+	if err != nil || len(collectErrors) > 0 || len(items) != 5 {
+		t.Fatalf("GetControllerItems() = %+v, %v, %v", items, collectErrors, err)
+	}
+	options := HistoryOptions{URL: server.URL + "/", BearerToken: "token", Selector: `cluster="prod"`}
+	if err := AddUsageHistory(context.Background(), NewClient(options.URL, options.BearerToken), items, options); err != nil {
+		t.Fatalf("AddUsageHistory() error = %v", err)
+	}
+
+	want := map[string][6]string{
+		"web":     {"150m", "200m", "500m", "100Mi", "250Mi", "300Mi"},
+		"web-api": {"1", "1", "1", "1Gi", "1Gi", "1Gi"},
+		"db":      {"500m", "750m", "1", "1Gi", "1Gi", "2Gi"},
+		"agent":   {"10m", "20m", "50m", "10Mi", "20Mi", "30Mi"},
+	}
+	for _, item := range items {
+		history := item.Container[0].History
+		quantiles, ok := want[item.Controller]
+		if !ok {
+			if history != nil {
+				t.Errorf("%s history = %+v, want none", item.Controller, history)
+			}
+			continue
+		}
+		if history == nil || history.Window != DefaultWindow {
+			t.Fatalf("%s history = %+v", item.Controller, history)
+		}
+		got := []resource.Quantity{history.CPU.P50, history.CPU.P95, history.CPU.Max, history.Memory.P50, history.Memory.P95, history.Memory.Max}
+		for index, quantity := range got {
+			if quantity.Cmp(resource.MustParse(quantiles[index])) != 0 {
+				t.Errorf("%s quantile %d = %s, want %s", item.Controller, index, quantity.String(), quantiles[index])
+			}
+		}
+	}
+
+	options.Selector = "invalid"
+	if err := AddUsageHistory(context.Background(), NewClient(options.URL, options.BearerToken), items, options); err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("AddUsageHistory() error = %v, want the error of prometheus", err)
+	}
+	for _, invalid := range []HistoryOptions{{}, {URL: server.URL, Window: "7 days"}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) returns no error", invalid)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"example.com/dev/k8s/controllers"
+	"github.com/xuri/excelize/v2"
+	"strconv"
+	"strings"
+)
+
+// formatDelta prefixes the positive values by "+".
+func formatDelta(value string) string {
+	if value != "0" && !strings.HasPrefix(value, "-") {
+		return "+" + value
+	}
+	return value
+}
+
+func generateDeltaInfo(resourceItem controllers.ResourceItem, units Units) []string {
+	result := generateResourceInfo(resourceItem, units)
+	for index, value := range result {
+		result[index] = formatDelta(value)
+	}
+	return result
+}
+
+// ConvertControllerDiffToCsv returns the header, a record with the total delta of every controller and a record with
+// the delta of every changed container, led by the source when the controllers come from several sources.
+func ConvertControllerDiffToCsv(diff controllers.ReportDiff, units Units) [][]string {
+	withSource := false
+	for _, controllerDiff := range diff.Controllers {
+		withSource = withSource || controllerDiff.Source != ""
+	}
+	headers := []string{"namespace", "controllerType", "controller", "change", "oldReplicas", "newReplicas", "containerType", "containerName"}
+	if withSource {
+		headers = append([]string{"source"}, headers...)
+	}
+	result := [][]string{append(headers, generateResourceHeaders("delta", units)...)}
+	for _, controllerDiff := range diff.Controllers {
+		controllerInfo := []string{controllerDiff.Namespace, controllerDiff.ControllerType, controllerDiff.Controller, controllerDiff.Change,
+			strconv.Itoa(int(controllerDiff.OldReplicas)), strconv.Itoa(int(controllerDiff.NewReplicas))}
+		if withSource {
+			controllerInfo = append([]string{controllerDiff.Source}, controllerInfo...)
+		}
+		result = append(result, append(append(append([]string{}, controllerInfo...), "total", ""), generateDeltaInfo(controllerDiff.TotalDelta, units)...))
+		for _, containerDiff := range controllerDiff.Containers {
+			record := append(append([]string{}, controllerInfo...), containerDiff.ContainerType, containerDiff.Name)
+			result = append(result, append(record, generateDeltaInfo(containerDiff.Delta, units)...))
+		}
+	}
+	return result
+}
+
+// ConvertNamespaceDiffToCsv returns the header and a record with the aggregated delta of every changed namespace.
+func ConvertNamespaceDiffToCsv(diff controllers.ReportDiff, units Units) [][]string {
+	headers := []string{"namespace", "added", "removed", "changed"}
+	result := [][]string{append(headers, generateResourceHeaders("delta", units)...)}
+	for _, namespaceDiff := range diff.Namespaces {
+		record := []string{namespaceDiff.Namespace, strconv.Itoa(namespaceDiff.Added), strconv.Itoa(namespaceDiff.Removed), strconv.Itoa(namespaceDiff.Changed)}
+		result = append(result, append(record, generateDeltaInfo(namespaceDiff.TotalDelta, units)...))
+	}
+	return result
+}
+
+// WriteDiffExcelFile writes the controller and the namespace deltas to separate sheets.
+func WriteDiffExcelFile(diff controllers.ReportDiff, filePath string, units Units) error {
+	excelFile := excelize.NewFile()
+	defer excelFile.Close()
+	sheets := []struct {
+		name    string
+		records [][]string
+	}{
+		{"controllers", ConvertControllerDiffToCsv(diff, units)},
+		{"namespaces", ConvertNamespaceDiffToCsv(diff, units)},
+	}
+	for sheetIndex, sheet := range sheets {
+		if sheetIndex == 0 {
+			if err := excelFile.SetSheetName(defaultSheet, sheet.name); err != nil {
+				return err
+			}
+		} else if _, err := excelFile.NewSheet(sheet.name); err != nil {
+			return err
+		}
+		for index, record := range sheet.records {
+			if err := writeExcelRow(excelFile, sheet.name, index+1, record); err != nil {
+				return err
+			}
+		}
+		if err := freezeHeader(excelFile, sheet.name, sheet.records[0], len(sheet.records)); err != nil {
+			return err
+		}
+	}
+	return saveExcelFile(excelFile, filePath)
+}
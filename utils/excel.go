@@ -0,0 +1,348 @@
+package utils
+
+import (
+	"example.com/dev/k8s/controllers"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	summarySheet = "summary"
+	groupsSheet  = "groups"
+	errorsSheet  = "errors"
+	// defaultSheet is created by excelize.NewFile, it is renamed to summarySheet
+	defaultSheet = "Sheet1"
+)
+
+// textHeaders are the columns which are written as text, all other columns are numbers.
+var textHeaders = map[string]bool{
+	"namespace": true, "controllerType": true, "controller": true, "storageNoSize": true, "unknownVolumes": true,
+	"persistentStorageClasses": true, "containerType": true, "containerName": true, "groupBy": true, "group": true,
+	"kind": true, "error": true, "name": true, "unschedulable": true, "autoscaler": true, "rolloutStrategy": true,
+	"source": true,
+}
+
+// excelCellValue returns value as a number unless the column of header is a text column.
+func excelCellValue(header, value string) interface{} {
+	if textHeaders[header] {
+		return value
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number
+	}
+	return value
+}
+
+// excelNumberFormat returns the number format showing the unit of the column of header, e.g. General" Mi".
+func (units Units) excelNumberFormat(header string) string {
+	if textHeaders[header] {
+		return ""
+	} else if strings.HasSuffix(header, "Ratio") {
+		return "0.0%"
+	} else if strings.Contains(strings.ToLower(header), "cpu") {
+		if units.CPU == cpuCores {
+			return `General" cores"`
+		}
+		return `General" m"`
+	} else if strings.HasSuffix(header, "("+units.Memory+")") {
+		return `General" ` + units.Memory + `"`
+	}
+	return "General"
+}
+
+// excelStyles creates the styles of a workbook once.
+type excelStyles struct {
+	excelFile     *excelize.File
+	header        int
+	numberFormats map[string]int
+}
+
+func newExcelStyles(excelFile *excelize.File) (*excelStyles, error) {
+	header, err := excelFile.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &excelStyles{excelFile: excelFile, header: header, numberFormats: map[string]int{}}, nil
+}
+
+func (styles *excelStyles) numberFormat(format string) (int, error) {
+	if style, ok := styles.numberFormats[format]; ok {
+		return style, nil
+	}
+	style, err := styles.excelFile.NewStyle(&excelize.Style{CustomNumFmt: &format})
+	if err != nil {
+		return 0, err
+	}
+	styles.numberFormats[format] = style
+	return style, nil
+}
+
+// setColumnStyles sets the number format of every numeric column of headers, starting at column 1.
+func (styles *excelStyles) setColumnStyles(sheet string, headers []string, units Units) error {
+	for index, header := range headers {
+		format := units.excelNumberFormat(header)
+		if format == "" {
+			continue
+		}
+		style, err := styles.numberFormat(format)
+		if err != nil {
+			return err
+		}
+		column, err := excelize.ColumnNumberToName(index + 1)
+		if err != nil {
+			return err
+		}
+		if err := styles.excelFile.SetColStyle(sheet, column, style); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes headers to rowIndex with the header style.
+func (styles *excelStyles) writeHeader(sheet string, rowIndex int, headers []string) error {
+	if err := writeExcelRow(styles.excelFile, sheet, rowIndex, headers); err != nil {
+		return err
+	}
+	start, err := excelize.CoordinatesToCellName(1, rowIndex)
+	if err != nil {
+		return err
+	}
+	end, err := excelize.CoordinatesToCellName(len(headers), rowIndex)
+	if err != nil {
+		return err
+	}
+	return styles.excelFile.SetCellStyle(sheet, start, end, styles.header)
+}
+
+// writeTable writes records, which start with the header, at rowIndex with the number formats of the headers, and
+// returns the row following the table.
+func (styles *excelStyles) writeTable(sheet string, rowIndex int, records [][]string, units Units) (int, error) {
+	headerRow := rowIndex
+	if err := styles.writeHeader(sheet, headerRow, records[0]); err != nil {
+		return 0, err
+	}
+	for _, record := range records[1:] {
+		rowIndex++
+		for index, value := range record {
+			cell, err := excelize.CoordinatesToCellName(index+1, rowIndex)
+			if err != nil {
+				return 0, err
+			}
+			if err := styles.excelFile.SetCellValue(sheet, cell, excelCellValue(records[0][index], value)); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if rowIndex == headerRow {
+		return rowIndex + 1, nil
+	}
+	for index, header := range records[0] {
+		format := units.excelNumberFormat(header)
+		if format == "" {
+			continue
+		}
+		style, err := styles.numberFormat(format)
+		if err != nil {
+			return 0, err
+		}
+		start, err := excelize.CoordinatesToCellName(index+1, headerRow+1)
+		if err != nil {
+			return 0, err
+		}
+		end, err := excelize.CoordinatesToCellName(index+1, rowIndex)
+		if err != nil {
+			return 0, err
+		}
+		if err := styles.excelFile.SetCellStyle(sheet, start, end, style); err != nil {
+			return 0, err
+		}
+	}
+	return rowIndex + 1, nil
+}
+
+// freezeHeader freezes the first row and adds an auto filter to the columns of headers.
+func freezeHeader(excelFile *excelize.File, sheet string, headers []string, rows int) error {
+	if err := excelFile.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	end, err := excelize.CoordinatesToCellName(len(headers), rows)
+	if err != nil {
+		return err
+	}
+	return excelFile.AutoFilter(sheet, "A1:"+end, nil)
+}
+
+// newHighlightStyle returns the conditional style of the cells which need attention: dark red on light red.
+func newHighlightStyle(excelFile *excelize.File) (int, error) {
+	return excelFile.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+	})
+}
+
+// highlightMissingResources fills the container columns of the containers without cpu/memory requests or limits.
+func highlightMissingResources(excelFile *excelize.File, sheet string, headers []string, rows int) error {
+	if rows < 2 {
+		return nil
+	}
+	// the container columns follow the controller columns
+	firstColumn := 1
+	for index, header := range headers {
+		if header == "containerType" {
+			firstColumn = index + 1
+		}
+	}
+	var conditions []string
+	for index := firstColumn + 2; index <= len(headers); index++ {
+		header := headers[index-1]
+		if strings.HasPrefix(header, "requestCpu") || strings.HasPrefix(header, "requestMem") ||
+			strings.HasPrefix(header, "limitCpu") || strings.HasPrefix(header, "limitMem") {
+			column, err := excelize.ColumnNumberToName(index)
+			if err != nil {
+				return err
+			}
+			conditions = append(conditions, fmt.Sprintf("$%s2=0", column))
+		}
+	}
+	style, err := newHighlightStyle(excelFile)
+	if err != nil {
+		return err
+	}
+	start, err := excelize.CoordinatesToCellName(firstColumn, 2)
+	if err != nil {
+		return err
+	}
+	end, err := excelize.CoordinatesToCellName(len(headers), rows)
+	if err != nil {
+		return err
+	}
+	return excelFile.SetConditionalFormat(sheet, start+":"+end, []excelize.ConditionalFormatOptions{
+		{Type: "formula", Criteria: "OR(" + strings.Join(conditions, ",") + ")", Format: style},
+	})
+}
+
+// pivotByNamespace returns the header and a record for every namespace with value summed by controller type.
+func pivotByNamespace(items []controllers.ControllerItem, value func(controllers.ControllerItem) string) [][]string {
+	var namespaces, types []string
+	sums := map[string]map[string]float64{}
+	typeSet := map[string]bool{}
+	for _, item := range items {
+		if _, ok := sums[item.Namespace]; !ok {
+			sums[item.Namespace] = map[string]float64{}
+			namespaces = append(namespaces, item.Namespace)
+		}
+		if !typeSet[item.ControllerType] {
+			typeSet[item.ControllerType] = true
+			types = append(types, item.ControllerType)
+		}
+		number, _ := strconv.ParseFloat(value(item), 64)
+		sums[item.Namespace][item.ControllerType] += number
+	}
+	sort.Strings(namespaces)
+	sort.Strings(types)
+	result := [][]string{append([]string{"namespace"}, types...)}
+	for _, namespace := range namespaces {
+		record := []string{namespace}
+		for _, controllerType := range types {
+			record = append(record, formatFloat(sums[namespace][controllerType]))
+		}
+		result = append(result, record)
+	}
+	return result
+}
+
+// addStackedChart adds a stacked bar chart of the pivot table written at rowIndex, one series per controller type.
+func addStackedChart(excelFile *excelize.File, sheet, cell, title string, rowIndex int, pivot [][]string) error {
+	if len(pivot) < 2 || len(pivot[0]) < 2 {
+		return nil
+	}
+	firstRow, lastRow := rowIndex+1, rowIndex+len(pivot)-1
+	var series []excelize.ChartSeries
+	for index := 2; index <= len(pivot[0]); index++ {
+		column, err := excelize.ColumnNumberToName(index)
+		if err != nil {
+			return err
+		}
+		series = append(series, excelize.ChartSeries{
+			Name:       fmt.Sprintf("'%s'!$%s$%d", sheet, column, rowIndex),
+			Categories: fmt.Sprintf("'%s'!$A$%d:$A$%d", sheet, firstRow, lastRow),
+			Values:     fmt.Sprintf("'%s'!$%s$%d:$%s$%d", sheet, column, firstRow, column, lastRow),
+		})
+	}
+	return excelFile.AddChart(sheet, cell, &excelize.Chart{
+		Type:      excelize.BarStacked,
+		Series:    series,
+		Title:     []excelize.RichTextRun{{Text: title}},
+		Legend:    excelize.ChartLegend{Position: "bottom"},
+		Dimension: excelize.ChartDimension{Width: 640, Height: 360},
+	})
+}
+
+// writeExcelOverview writes the totals by namespace, by controller type and by source when the controllers were built
+// from kustomizations, and the charts of the requests by namespace to the summary sheet.
+func writeExcelOverview(styles *excelStyles, items []controllers.ControllerItem, units Units) error {
+	excelFile := styles.excelFile
+	namespaceRecords := ConvertSummaryToCsv(controllers.AggregateControllerItems(items, controllers.GroupByNamespace), units)
+	rowIndex, err := styles.writeTable(summarySheet, 1, namespaceRecords, units)
+	if err != nil {
+		return err
+	}
+	rowIndex, err = styles.writeTable(summarySheet, rowIndex+1, ConvertSummaryToCsv(controllers.AggregateControllerItems(items, controllers.GroupByType), units), units)
+	if err != nil {
+		return err
+	}
+	if hasSource(items) {
+		rowIndex, err = styles.writeTable(summarySheet, rowIndex+1, ConvertSummaryToCsv(controllers.AggregateControllerItems(items, controllers.GroupBySource), units), units)
+		if err != nil {
+			return err
+		}
+	}
+	chartColumn, err := excelize.ColumnNumberToName(len(namespaceRecords[0]) + 2)
+	if err != nil {
+		return err
+	}
+	charts := []struct {
+		title string
+		value func(controllers.ControllerItem) string
+	}{
+		{units.CPUHeader("requestCpu") + " by namespace", func(item controllers.ControllerItem) string {
+			return units.FormatCPU(item.Total.RequestCPU)
+		}},
+		{units.MemoryHeader("requestMem") + " by namespace", func(item controllers.ControllerItem) string {
+			return units.FormatMemory(item.Total.RequestMem)
+		}},
+	}
+	chartRow := 1
+	for _, chart := range charts {
+		pivot := pivotByNamespace(items, chart.value)
+		pivotRow := rowIndex + 1
+		if rowIndex, err = styles.writeTable(summarySheet, pivotRow, pivot, units); err != nil {
+			return err
+		} else if err := writeExcelRow(excelFile, summarySheet, pivotRow, append([]string{chart.title}, pivot[0][1:]...)); err != nil {
+			return err
+		}
+		if err := addStackedChart(excelFile, summarySheet, fmt.Sprintf("%s%d", chartColumn, chartRow), chart.title, pivotRow, pivot); err != nil {
+			return err
+		}
+		chartRow += 20
+	}
+	return nil
+}
+
+// writeExcelRecords writes records, which start with the header, to a new sheet with a frozen header.
+func writeExcelRecords(styles *excelStyles, sheet string, records [][]string, units Units) error {
+	if _, err := styles.excelFile.NewSheet(sheet); err != nil {
+		return err
+	}
+	if _, err := styles.writeTable(sheet, 1, records, units); err != nil {
+		return err
+	}
+	return freezeHeader(styles.excelFile, sheet, records[0], len(records))
+}
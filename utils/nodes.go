@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"example.com/dev/k8s/controllers"
+	"github.com/xuri/excelize/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	nodesSheet = "nodes"
+	// unscheduledNode is the row of the pending pods which are not scheduled on any node
+	unscheduledNode = "<unscheduled>"
+)
+
+// nodeResourceNames returns cpu, memory, ephemeral-storage, pods and the other resources of the nodes sorted by name.
+func nodeResourceNames(report controllers.NodeReport) []v1.ResourceName {
+	names := []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourceEphemeralStorage, v1.ResourcePods}
+	known := map[v1.ResourceName]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+	var extended []v1.ResourceName
+	for name := range report.Total.Allocatable {
+		if !known[name] {
+			known[name] = true
+			extended = append(extended, name)
+		}
+	}
+	sort.Slice(extended, func(i, j int) bool {
+		return extended[i] < extended[j]
+	})
+	return append(names, extended...)
+}
+
+func isMemoryResource(name v1.ResourceName) bool {
+	return name == v1.ResourceMemory || name == v1.ResourceEphemeralStorage || strings.HasPrefix(string(name), v1.ResourceHugePagesPrefix)
+}
+
+// nodeResourceHeader returns the header of field of a resource in camel case with its unit, e.g. memAllocatable(Mi).
+func nodeResourceHeader(name v1.ResourceName, field string, units Units) string {
+	prefix := string(name)
+	if name == v1.ResourceMemory {
+		prefix = "mem"
+	} else if name == v1.ResourceEphemeralStorage {
+		prefix = "ephemeralStorage"
+	}
+	header := prefixHeader(prefix, field)
+	if strings.HasSuffix(field, "Ratio") {
+		return header
+	} else if name == v1.ResourceCPU {
+		return units.CPUHeader(header)
+	} else if isMemoryResource(name) {
+		return units.MemoryHeader(header)
+	}
+	return header
+}
+
+func formatNodeResource(name v1.ResourceName, quantity resource.Quantity, units Units) string {
+	if name == v1.ResourceCPU {
+		return units.FormatCPU(quantity)
+	} else if isMemoryResource(name) {
+		return units.FormatMemory(quantity)
+	}
+	return quantity.String()
+}
+
+// nodeResourceFields returns the csv fields of the resource name, pods have no limits to compare to the allocatable.
+func nodeResourceFields(name v1.ResourceName) []string {
+	if name == v1.ResourcePods {
+		return []string{"capacity", "allocatable", "requests", "limits", "requestRatio"}
+	}
+	return []string{"capacity", "allocatable", "requests", "limits", "requestRatio", "limitRatio"}
+}
+
+// nodeCsvRecord returns the csv record of node, unschedulable is empty for the rows which are not a node.
+func nodeCsvRecord(node controllers.NodeItem, unschedulable string, names []v1.ResourceName, units Units) []string {
+	record := []string{node.Name, unschedulable}
+	for _, name := range names {
+		record = append(record, formatNodeResource(name, node.Capacity[name], units), formatNodeResource(name, node.Allocatable[name], units),
+			formatNodeResource(name, node.Requests[name], units), formatNodeResource(name, node.Limits[name], units))
+		requestRatio, hasRatio := node.RequestRatios[name]
+		limitRatio := node.LimitRatios[name]
+		if hasRatio {
+			record = append(record, formatRatio(&requestRatio))
+		} else {
+			record = append(record, "")
+		}
+		if name == v1.ResourcePods {
+			continue
+		} else if hasRatio {
+			record = append(record, formatRatio(&limitRatio))
+		} else {
+			record = append(record, "")
+		}
+	}
+	return record
+}
+
+// unscheduledNodeItem returns the requests of the pending pods which are not scheduled on any node as a node, false
+// when there is none.
+func unscheduledNodeItem(report controllers.NodeReport) (controllers.NodeItem, bool) {
+	if pods, ok := report.UnscheduledRequests[v1.ResourcePods]; ok && !pods.IsZero() {
+		return controllers.NodeItem{Name: unscheduledNode, Requests: report.UnscheduledRequests}, true
+	}
+	return controllers.NodeItem{}, false
+}
+
+// ConvertNodesToCsv returns the header and a record for every node with the capacity, allocatable, requests, limits
+// and ratios of every resource, followed by the pending pods and the total.
+func ConvertNodesToCsv(report controllers.NodeReport, units Units) [][]string {
+	names := nodeResourceNames(report)
+	headers := []string{"name", "unschedulable"}
+	for _, name := range names {
+		for _, field := range nodeResourceFields(name) {
+			headers = append(headers, nodeResourceHeader(name, field, units))
+		}
+	}
+	result := [][]string{headers}
+	for _, node := range report.Nodes {
+		result = append(result, nodeCsvRecord(node, strconv.FormatBool(node.Unschedulable), names, units))
+	}
+	if unscheduled, ok := unscheduledNodeItem(report); ok {
+		result = append(result, nodeCsvRecord(unscheduled, "", names, units))
+	}
+	return append(result, nodeCsvRecord(report.Total, "", names, units))
+}
+
+// formatAllocation returns value with its percentage of the allocatable, e.g. 1500 (38%), like kubectl describe node.
+func formatAllocation(value string, ratios map[v1.ResourceName]float64, name v1.ResourceName) string {
+	if ratio, ok := ratios[name]; ok {
+		return value + " (" + strconv.Itoa(int(ratio*100+0.5)) + "%)"
+	}
+	return value
+}
+
+// nodeTableRecord returns the table record of node, wide adds the ephemeral storage and the extended resources.
+func nodeTableRecord(node controllers.NodeItem, names []v1.ResourceName, units Units, wide bool) []string {
+	record := []string{node.Name}
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		record = append(record, formatNodeResource(name, node.Allocatable[name], units),
+			formatAllocation(formatNodeResource(name, node.Requests[name], units), node.RequestRatios, name),
+			formatAllocation(formatNodeResource(name, node.Limits[name], units), node.LimitRatios, name))
+	}
+	pods := node.Requests[v1.ResourcePods]
+	podsAllocatable := node.Allocatable[v1.ResourcePods]
+	record = append(record, pods.String()+"/"+podsAllocatable.String())
+	if wide {
+		record = append(record, strconv.FormatBool(node.Unschedulable),
+			formatNodeResource(v1.ResourceEphemeralStorage, node.Allocatable[v1.ResourceEphemeralStorage], units),
+			formatAllocation(formatNodeResource(v1.ResourceEphemeralStorage, node.Requests[v1.ResourceEphemeralStorage], units), node.RequestRatios, v1.ResourceEphemeralStorage))
+		for _, name := range names[4:] {
+			record = append(record, formatNodeResource(name, node.Requests[name], units)+"/"+formatNodeResource(name, node.Allocatable[name], units))
+		}
+	}
+	return record
+}
+
+// ConvertNodesToTable returns the header, a record for every node with the allocatable cpu and memory, the requests
+// and limits with their percentage of the allocatable and the pods, followed by the pending pods and the total.
+func ConvertNodesToTable(report controllers.NodeReport, units Units, wide bool) [][]string {
+	names := nodeResourceNames(report)
+	headers := []string{"NAME", units.CPUHeader("CPU ALLOC"), units.CPUHeader("CPU REQ"), units.CPUHeader("CPU LIM"),
+		units.MemoryHeader("MEM ALLOC"), units.MemoryHeader("MEM REQ"), units.MemoryHeader("MEM LIM"), "PODS"}
+	if wide {
+		headers = append(headers, "UNSCHEDULABLE", units.MemoryHeader("EPHEMERAL ALLOC"), units.MemoryHeader("EPHEMERAL REQ"))
+		for _, name := range names[4:] {
+			headers = append(headers, strings.ToUpper(string(name))+" REQ/ALLOC")
+		}
+	}
+	result := [][]string{headers}
+	for _, node := range report.Nodes {
+		result = append(result, nodeTableRecord(node, names, units, wide))
+	}
+	if unscheduled, ok := unscheduledNodeItem(report); ok {
+		result = append(result, nodeTableRecord(unscheduled, names, units, wide))
+	}
+	return append(result, nodeTableRecord(report.Total, names, units, wide))
+}
+
+// WriteNodesExcelFile writes the nodes, the pending pods and the total with a frozen header, the limit ratios above 1
+// are highlighted as overcommitted.
+func WriteNodesExcelFile(report controllers.NodeReport, filePath string, units Units) error {
+	excelFile := excelize.NewFile()
+	defer excelFile.Close()
+	styles, err := newExcelStyles(excelFile)
+	if err != nil {
+		return err
+	}
+	if err := excelFile.SetSheetName(defaultSheet, nodesSheet); err != nil {
+		return err
+	}
+	records := ConvertNodesToCsv(report, units)
+	if _, err := styles.writeTable(nodesSheet, 1, records, units); err != nil {
+		return err
+	}
+	if err := freezeHeader(excelFile, nodesSheet, records[0], len(records)); err != nil {
+		return err
+	}
+	if len(records) > 1 {
+		style, err := newHighlightStyle(excelFile)
+		if err != nil {
+			return err
+		}
+		for index, header := range records[0] {
+			if !strings.HasSuffix(header, "LimitRatio") {
+				continue
+			}
+			start, err := excelize.CoordinatesToCellName(index+1, 2)
+			if err != nil {
+				return err
+			}
+			end, err := excelize.CoordinatesToCellName(index+1, len(records))
+			if err != nil {
+				return err
+			}
+			err = excelFile.SetConditionalFormat(nodesSheet, start+":"+end, []excelize.ConditionalFormatOptions{
+				{Type: "cell", Criteria: ">", Value: "1", Format: style},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return saveExcelFile(excelFile, filePath)
+}
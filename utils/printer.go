@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"encoding/json"
+	"example.com/dev/k8s/controllers"
+	"fmt"
+	"io"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/util/jsonpath"
+	"strings"
+	"text/template"
+)
+
+const (
+	GoTemplatePrefix    = "go-template="
+	JsonPathPrefix      = "jsonpath="
+	CustomColumnsPrefix = "custom-columns="
+	// noneValue is printed by custom columns which have no value, the same as kubectl
+	noneValue = "<none>"
+)
+
+type customColumn struct {
+	header string
+	parser *jsonpath.JSONPath
+}
+
+// TemplatePrinter prints a report like the kubectl printers: a go template or a JSONPath template over the json
+// report, or custom columns with a JSONPath over every controller of the report.
+type TemplatePrinter struct {
+	goTemplate *template.Template
+	jsonPath   *jsonpath.JSONPath
+	columns    []customColumn
+}
+
+// relaxedJsonPath wraps expression by {} if it has none, e.g. .controller is {.controller}.
+func relaxedJsonPath(expression string) string {
+	if strings.Contains(expression, "{") {
+		return expression
+	}
+	return "{" + expression + "}"
+}
+
+func parseJsonPath(name, expression string) (*jsonpath.JSONPath, error) {
+	parser := jsonpath.New(name).AllowMissingKeys(true)
+	if err := parser.Parse(relaxedJsonPath(expression)); err != nil {
+		return nil, fmt.Errorf("parse jsonpath %q failed: %v", expression, err)
+	}
+	return parser, nil
+}
+
+// templateFuncs formats the quantities of the json report in units, e.g. {{cpu .total.requestCpu}}.
+func templateFuncs(units Units) template.FuncMap {
+	parse := func(value interface{}) (resource.Quantity, error) {
+		return resource.ParseQuantity(fmt.Sprint(value))
+	}
+	return template.FuncMap{
+		"cpu": func(value interface{}) (string, error) {
+			quantity, err := parse(value)
+			return units.FormatCPU(quantity), err
+		},
+		"memory": func(value interface{}) (string, error) {
+			quantity, err := parse(value)
+			return units.FormatMemory(quantity), err
+		},
+	}
+}
+
+// NewTemplatePrinter parses output of go-template=TEMPLATE, jsonpath=TEMPLATE or custom-columns=HEADER:JSONPATH,...
+// It returns nil if output is none of them.
+func NewTemplatePrinter(output string, units Units) (*TemplatePrinter, error) {
+	printer := &TemplatePrinter{}
+	if strings.HasPrefix(output, GoTemplatePrefix) {
+		goTemplate, err := template.New("output").Funcs(templateFuncs(units)).Parse(strings.TrimPrefix(output, GoTemplatePrefix))
+		if err != nil {
+			return nil, fmt.Errorf("parse go template failed: %v", err)
+		}
+		printer.goTemplate = goTemplate
+	} else if strings.HasPrefix(output, JsonPathPrefix) {
+		parser, err := parseJsonPath("output", strings.TrimPrefix(output, JsonPathPrefix))
+		if err != nil {
+			return nil, err
+		}
+		printer.jsonPath = parser
+	} else if strings.HasPrefix(output, CustomColumnsPrefix) {
+		spec := strings.TrimPrefix(output, CustomColumnsPrefix)
+		if spec == "" {
+			return nil, fmt.Errorf("custom-columns format specified but no custom columns given")
+		}
+		for _, column := range strings.Split(spec, ",") {
+			header, expression, ok := strings.Cut(column, ":")
+			if !ok || header == "" || expression == "" {
+				return nil, fmt.Errorf("unexpected custom-columns spec %q, expected HEADER:JSONPATH", column)
+			}
+			parser, err := parseJsonPath(header, expression)
+			if err != nil {
+				return nil, err
+			}
+			printer.columns = append(printer.columns, customColumn{header: header, parser: parser})
+		}
+	} else {
+		return nil, nil
+	}
+	return printer, nil
+}
+
+// jsonData returns content decoded from its json, so that the templates use the json field names.
+func jsonData(content interface{}) (interface{}, error) {
+	contentJson, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	err = json.Unmarshal(contentJson, &data)
+	return data, err
+}
+
+// customColumnValue returns the values found by parser joined by ",".
+func customColumnValue(parser *jsonpath.JSONPath, data interface{}) (string, error) {
+	results, err := parser.FindResults(data)
+	if err != nil {
+		return "", err
+	}
+	var values []string
+	for _, result := range results {
+		for _, value := range result {
+			values = append(values, fmt.Sprint(value.Interface()))
+		}
+	}
+	if len(values) == 0 {
+		return noneValue, nil
+	}
+	return strings.Join(values, ","), nil
+}
+
+func (printer *TemplatePrinter) Print(w io.Writer, report controllers.Report) error {
+	if printer.goTemplate != nil || printer.jsonPath != nil {
+		data, err := jsonData(report)
+		if err != nil {
+			return err
+		}
+		if printer.goTemplate != nil {
+			return printer.goTemplate.Execute(w, data)
+		}
+		return printer.jsonPath.Execute(w, data)
+	}
+	headers := make([]string, 0, len(printer.columns))
+	for _, column := range printer.columns {
+		headers = append(headers, column.header)
+	}
+	records := [][]string{headers}
+	for _, controllerItem := range report.Responses {
+		data, err := jsonData(controllerItem)
+		if err != nil {
+			return err
+		}
+		record := make([]string, 0, len(printer.columns))
+		for _, column := range printer.columns {
+			value, err := customColumnValue(column.parser, data)
+			if err != nil {
+				return fmt.Errorf("custom column %s of %s/%s failed: %v", column.header, controllerItem.Namespace, controllerItem.Controller, err)
+			}
+			record = append(record, value)
+		}
+		records = append(records, record)
+	}
+	return WriteTable(w, records)
+}
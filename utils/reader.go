@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"example.com/dev/k8s/controllers"
+	"k8s.io/klog/v2"
+	"os"
+)
+
+// isLegacyReport returns whether content was written before the exact quantities were stored, the resources of its
+// containers are plain numbers instead of quantity strings.
+func isLegacyReport(content []byte) (bool, error) {
+	var report struct {
+		Responses []struct {
+			InitContainer []map[string]json.RawMessage `json:"initContainer"`
+			Container     []map[string]json.RawMessage `json:"container"`
+			EmptyDir      json.RawMessage              `json:"emptyDir"`
+			Storage       json.RawMessage              `json:"storage"`
+		} `json:"responses"`
+	}
+	if err := json.Unmarshal(content, &report); err != nil {
+		return false, err
+	}
+	isNumber := func(value json.RawMessage) bool {
+		value = bytes.TrimSpace(value)
+		return len(value) > 0 && (value[0] == '-' || (value[0] >= '0' && value[0] <= '9'))
+	}
+	for _, item := range report.Responses {
+		if isNumber(item.EmptyDir) || isNumber(item.Storage) {
+			return true, nil
+		}
+		for _, container := range append(item.InitContainer, item.Container...) {
+			for _, value := range container {
+				if isNumber(value) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// ReadJsonReport reads a report written by WriteJsonFile. The legacy reports, whose cpu is in millicores and memory
+// in MiB, are converted to the exact quantities.
+func ReadJsonReport(filePath string) (controllers.Report, error) {
+	var report controllers.Report
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return report, err
+	}
+	legacy, err := isLegacyReport(content)
+	if err != nil {
+		return report, err
+	} else if !legacy {
+		err = json.Unmarshal(content, &report)
+		return report, err
+	}
+	klog.Warningf("%s is a legacy report, its millicores and MiB are converted to quantities", filePath)
+	var legacyReport struct {
+		Responses []controllers.LegacyControllerItem `json:"responses"`
+	}
+	if err := json.Unmarshal(content, &legacyReport); err != nil {
+		return report, err
+	}
+	for _, item := range legacyReport.Responses {
+		report.Responses = append(report.Responses, item.ControllerItem())
+	}
+	return report, nil
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"example.com/dev/k8s/controllers"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ConvertRecommendationsToCsv returns the header and a record for every recommendation with the current and the
+// recommended requests and limits, and the usage history.
+func ConvertRecommendationsToCsv(recommendations []controllers.Recommendation, units Units) [][]string {
+	result := [][]string{{"namespace", "controllerType", "controller", "containerType", "containerName", "policy",
+		units.CPUHeader("requestCpu"), units.CPUHeader("recommendedRequestCpu"), units.CPUHeader("limitCpu"), units.CPUHeader("recommendedLimitCpu"),
+		units.MemoryHeader("requestMem"), units.MemoryHeader("recommendedRequestMem"), units.MemoryHeader("limitMem"), units.MemoryHeader("recommendedLimitMem"),
+		"window", units.CPUHeader("p50Cpu"), units.CPUHeader("p95Cpu"), units.CPUHeader("maxCpu"),
+		units.MemoryHeader("p50Mem"), units.MemoryHeader("p95Mem"), units.MemoryHeader("maxMem")}}
+	for _, recommendation := range recommendations {
+		current, recommended, history := recommendation.Current, recommendation.Recommended, recommendation.History
+		result = append(result, []string{recommendation.Namespace, recommendation.ControllerType, recommendation.Controller,
+			recommendation.ContainerType, recommendation.Container, recommendation.Policy,
+			units.FormatCPU(current.RequestCPU), units.FormatCPU(recommended.RequestCPU), units.FormatCPU(current.LimitCPU), units.FormatCPU(recommended.LimitCPU),
+			units.FormatMemory(current.RequestMem), units.FormatMemory(recommended.RequestMem), units.FormatMemory(current.LimitMem), units.FormatMemory(recommended.LimitMem),
+			history.Window, units.FormatCPU(history.CPU.P50), units.FormatCPU(history.CPU.P95), units.FormatCPU(history.CPU.Max),
+			units.FormatMemory(history.Memory.P50), units.FormatMemory(history.Memory.P95), units.FormatMemory(history.Memory.Max)})
+	}
+	return result
+}
+
+// formatChange returns old->new, or the value alone when it is unchanged.
+func formatChange(format func(resource.Quantity) string, old, new resource.Quantity) string {
+	if old.Cmp(new) == 0 {
+		return format(new)
+	}
+	return format(old) + "->" + format(new)
+}
+
+// ConvertRecommendationsToTable returns the header and a record for every recommendation with the changes of the
+// requests and limits, and the p95 and max usage.
+func ConvertRecommendationsToTable(recommendations []controllers.Recommendation, units Units) [][]string {
+	result := [][]string{{"NAMESPACE", "TYPE", "NAME", "CONTAINER", "POLICY",
+		units.CPUHeader("CPU REQ"), units.CPUHeader("CPU LIM"), units.MemoryHeader("MEM REQ"), units.MemoryHeader("MEM LIM"),
+		units.CPUHeader("CPU P95"), units.CPUHeader("CPU MAX"), units.MemoryHeader("MEM P95"), units.MemoryHeader("MEM MAX")}}
+	for _, recommendation := range recommendations {
+		current, recommended, history := recommendation.Current, recommendation.Recommended, recommendation.History
+		result = append(result, []string{recommendation.Namespace, recommendation.ControllerType, recommendation.Controller,
+			recommendation.Container, recommendation.Policy,
+			formatChange(units.FormatCPU, current.RequestCPU, recommended.RequestCPU), formatChange(units.FormatCPU, current.LimitCPU, recommended.LimitCPU),
+			formatChange(units.FormatMemory, current.RequestMem, recommended.RequestMem), formatChange(units.FormatMemory, current.LimitMem, recommended.LimitMem),
+			units.FormatCPU(history.CPU.P95), units.FormatCPU(history.CPU.Max), units.FormatMemory(history.Memory.P95), units.FormatMemory(history.Memory.Max)})
+	}
+	return result
+}
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"example.com/dev/k8s/controllers"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// generateHeaders returns the controller headers followed by the container headers, usage adds the usage of the
+// metrics API to both, autoscaler adds the replica range and the max scale footprint to the controller headers, and
+// rollout the update strategy and the rollout peak.
+func generateHeaders(units Units, usage, autoscaler, rollout bool) []string {
+	headers := []string{"namespace", "controllerType", "controller", "replicas",
+		units.MemoryHeader("emptyDir"), units.MemoryHeader("storage"), "storageNoSize", "unknownVolumes",
+		units.MemoryHeader("persistentStorageRequest"), units.MemoryHeader("persistentStorageCapacity"), "persistentStorageClasses"}
+	headers = append(headers, generateResourceHeaders("pod", units)...)
+	headers = append(headers, generateResourceHeaders("total", units)...)
+	if autoscaler {
+		headers = append(append(headers, "autoscaler", "minReplicas", "maxReplicas"), generateResourceHeaders("maxTotal", units)...)
+	}
+	if rollout {
+		headers = append(append(headers, "rolloutStrategy", "surge", "peakReplicas"), generateResourceHeaders("peakTotal", units)...)
+	}
+	if usage {
+		headers = append(append(headers, "usagePods"), generateUsageHeaders("total", units)...)
+	}
+	headers = append(headers, "containerType", "containerName")
+	headers = append(headers, generateResourceHeaders("", units)...)
+	if usage {
+		headers = append(headers, generateUsageHeaders("", units)...)
+	}
+	return headers
+}
+
+// prefixHeader returns name prefixed in camel case, e.g. totalRequestCpu.
+func prefixHeader(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + strings.ToUpper(name[:1]) + name[1:]
+}
+
+func generateResourceHeaders(prefix string, units Units) []string {
+	header := func(name string) string {
+		return prefixHeader(prefix, name)
+	}
+	return []string{
+		units.CPUHeader(header("requestCpu")), units.MemoryHeader(header("requestMem")), units.MemoryHeader(header("requestEphemeralStorage")),
+		units.CPUHeader(header("limitCpu")), units.MemoryHeader(header("limitMem")), units.MemoryHeader(header("limitEphemeralStorage")),
+	}
+}
+
+func generateResourceInfo(resourceItem controllers.ResourceItem, units Units) []string {
+	return []string{
+		units.FormatCPU(resourceItem.RequestCPU), units.FormatMemory(resourceItem.RequestMem), units.FormatMemory(resourceItem.RequestEphemeralStorate),
+		units.FormatCPU(resourceItem.LimitCPU), units.FormatMemory(resourceItem.LimitMem), units.FormatMemory(resourceItem.LimitEphemeralStorate),
+	}
+}
+
+func generateUsageHeaders(prefix string, units Units) []string {
+	header := func(name string) string {
+		return prefixHeader(prefix, name)
+	}
+	return []string{units.CPUHeader(header("usageCpu")), units.MemoryHeader(header("usageMem")),
+		header("cpuRequestRatio"), header("cpuLimitRatio"), header("memRequestRatio"), header("memLimitRatio")}
+}
+
+// formatRatio returns ratio rounded to 3 decimals, empty if it is not set.
+func formatRatio(ratio *float64) string {
+	if ratio == nil {
+		return ""
+	}
+	return formatFloat(math.Round(*ratio*1000) / 1000)
+}
+
+// generateUsageInfo returns the usage and its ratios, empty values if usage is nil.
+func generateUsageInfo(usage *controllers.UsageItem, units Units) []string {
+	if usage == nil {
+		return []string{"", "", "", "", "", ""}
+	}
+	return []string{units.FormatCPU(usage.CPU), units.FormatMemory(usage.Memory),
+		formatRatio(usage.CPURequestRatio), formatRatio(usage.CPULimitRatio), formatRatio(usage.MemoryRequestRatio), formatRatio(usage.MemoryLimitRatio)}
+}
+
+// hasUsage reports whether any controller has the usage of the metrics API.
+func hasUsage(content []controllers.ControllerItem) bool {
+	for _, controllerItem := range content {
+		if controllerItem.Usage != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSource reports whether any controller was built from a kustomization.
+func hasSource(content []controllers.ControllerItem) bool {
+	for _, controllerItem := range content {
+		if controllerItem.Source != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAutoscaler reports whether any controller is scaled by an autoscaler.
+func hasAutoscaler(content []controllers.ControllerItem) bool {
+	for _, controllerItem := range content {
+		if controllerItem.Autoscaler != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAutoscalerInfo returns the autoscaler name, the replica range and the max scale footprint, which are the
+// current ones without autoscaler.
+func generateAutoscalerInfo(controllerItem controllers.ControllerItem, units Units) []string {
+	result := []string{"", strconv.Itoa(int(controllerItem.Replicas)), strconv.Itoa(int(controllerItem.Replicas))}
+	if autoscaler := controllerItem.Autoscaler; autoscaler != nil {
+		result = []string{autoscaler.Name, strconv.Itoa(int(autoscaler.MinReplicas)), strconv.Itoa(int(autoscaler.MaxReplicas))}
+	}
+	return append(result, generateResourceInfo(controllerItem.MaxTotal(), units)...)
+}
+
+// hasRollout reports whether any controller has an update strategy.
+func hasRollout(content []controllers.ControllerItem) bool {
+	for _, controllerItem := range content {
+		if controllerItem.Rollout != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// generateRolloutInfo returns the update strategy, the surge and the rollout peak, which are the current replicas and
+// footprint without update strategy.
+func generateRolloutInfo(controllerItem controllers.ControllerItem, units Units) []string {
+	result := []string{"", "", strconv.Itoa(int(controllerItem.PeakReplicas()))}
+	if rollout := controllerItem.Rollout; rollout != nil {
+		result[0], result[1] = rollout.Strategy, strconv.Itoa(int(rollout.Surge))
+	}
+	return append(result, generateResourceInfo(controllerItem.PeakTotal(), units)...)
+}
+
+// generatePersistentStorageInfo returns the total request, the total bound capacity and the request of every
+// storage class formatted as class:request joined by ";".
+func generatePersistentStorageInfo(items []controllers.StorageClassItem, units Units) []string {
+	var request, capacity resource.Quantity
+	storageClasses := make([]string, 0, len(items))
+	for _, item := range items {
+		request.Add(item.Request)
+		capacity.Add(item.Capacity)
+		storageClasses = append(storageClasses, item.StorageClass+":"+units.FormatMemory(item.Request))
+	}
+	return []string{units.FormatMemory(request), units.FormatMemory(capacity), strings.Join(storageClasses, ";")}
+}
+
+func generateControllerInfo(controllerItem controllers.ControllerItem, units Units, usage, autoscaler, rollout bool) []string {
+	result := []string{
+		controllerItem.Namespace, controllerItem.ControllerType, controllerItem.Controller, strconv.Itoa(int(controllerItem.Replicas)),
+		units.FormatMemory(controllerItem.EmptyDir), units.FormatMemory(controllerItem.Storage), strconv.FormatBool(controllerItem.StorageNoSize),
+		strings.Join(controllerItem.UnknownVolumes, ";"),
+	}
+	result = append(result, generatePersistentStorageInfo(controllerItem.PersistentStorage, units)...)
+	result = append(result, generateResourceInfo(controllerItem.EffectivePod, units)...)
+	result = append(result, generateResourceInfo(controllerItem.Total, units)...)
+	if autoscaler {
+		result = append(result, generateAutoscalerInfo(controllerItem, units)...)
+	}
+	if rollout {
+		result = append(result, generateRolloutInfo(controllerItem, units)...)
+	}
+	if usage {
+		pods := ""
+		if controllerItem.Usage != nil {
+			pods = strconv.Itoa(controllerItem.Usage.Pods)
+		}
+		result = append(append(result, pods), generateUsageInfo(controllerItem.Usage, units)...)
+	}
+	return result
+}
+
+func generateContainerInfo(controllerItem controllers.ControllerItem, units Units, usage bool) [][]string {
+	var result [][]string
+	containerInfo := func(containerType string, container controllers.ContainerItem) []string {
+		info := append([]string{containerType, container.Name}, generateResourceInfo(container.ResourceItem, units)...)
+		if usage {
+			info = append(info, generateUsageInfo(container.Usage, units)...)
+		}
+		return info
+	}
+	for _, container := range controllerItem.InitContainer {
+		result = append(result, containerInfo("initContainer", container))
+	}
+	for _, container := range controllerItem.Container {
+		result = append(result, containerInfo("container", container))
+	}
+	return result
+}
+
+// ConvertResultToCsv returns the header and a record for every container, the controller fields are repeated. The usage
+// columns are added when any controller has the usage of the metrics API, the autoscaler columns when any controller
+// has an autoscaler, and the rollout columns when any controller has an update strategy. The source column leads when
+// any controller was built from a kustomization.
+func ConvertResultToCsv(content []controllers.ControllerItem, units Units) [][]string {
+	usage, autoscaler, rollout, source := hasUsage(content), hasAutoscaler(content), hasRollout(content), hasSource(content)
+	headers := generateHeaders(units, usage, autoscaler, rollout)
+	if source {
+		headers = append([]string{"source"}, headers...)
+	}
+	result := [][]string{headers}
+	for _, controllerItem := range content {
+		controllerInfo := generateControllerInfo(controllerItem, units, usage, autoscaler, rollout)
+		if source {
+			controllerInfo = append([]string{controllerItem.Source}, controllerInfo...)
+		}
+		for _, containerInfo := range generateContainerInfo(controllerItem, units, usage) {
+			result = append(result, append(append([]string{}, controllerInfo...), containerInfo...))
+		}
+	}
+	return result
+}
+
+// ConvertErrorsToCsv returns the header and a record for every collect error.
+func ConvertErrorsToCsv(collectErrors []controllers.CollectError) [][]string {
+	result := [][]string{{"namespace", "kind", "error"}}
+	for _, collectError := range collectErrors {
+		result = append(result, []string{collectError.Namespace, collectError.Kind, collectError.Message})
+	}
+	return result
+}
+
+// ConvertSummaryToCsv returns the header and a record for every group, the max scale columns are added when any group
+// has an autoscaler, the rollout peak columns when any group surges during a rollout.
+func ConvertSummaryToCsv(groups []controllers.GroupItem, units Units) [][]string {
+	autoscaler, rollout := false, false
+	for _, group := range groups {
+		autoscaler = autoscaler || group.Autoscalers > 0
+		rollout = rollout || group.PeakReplicas > group.Replicas
+	}
+	headers := []string{"groupBy", "group", "controllers", "replicas"}
+	headers = append(headers, generateResourceHeaders("total", units)...)
+	headers = append(headers, units.MemoryHeader("emptyDir"), units.MemoryHeader("storage"), units.MemoryHeader("persistentStorage"))
+	if autoscaler {
+		headers = append(append(headers, "autoscalers", "maxReplicas"), generateResourceHeaders("maxTotal", units)...)
+	}
+	if rollout {
+		headers = append(append(headers, "peakReplicas"), generateResourceHeaders("peakTotal", units)...)
+	}
+	result := [][]string{headers}
+	for _, group := range groups {
+		record := []string{group.GroupBy, group.Group, strconv.Itoa(group.Controllers), strconv.Itoa(int(group.Replicas))}
+		record = append(record, generateResourceInfo(group.Total, units)...)
+		record = append(record, units.FormatMemory(group.EmptyDir), units.FormatMemory(group.Storage), units.FormatMemory(group.PersistentStorage))
+		if autoscaler {
+			record = append(append(record, strconv.Itoa(group.Autoscalers), strconv.Itoa(int(group.MaxReplicas))), generateResourceInfo(group.MaxTotal, units)...)
+		}
+		if rollout {
+			record = append(append(record, strconv.Itoa(int(group.PeakReplicas))), generateResourceInfo(group.PeakTotal, units)...)
+		}
+		result = append(result, record)
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"example.com/dev/k8s/controllers"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"strconv"
+)
+
+// ConvertSimulationToCsv returns the header and a record for every pool with the node shape, the number of nodes
+// needed, the DaemonSet overhead of a node, the allocatable, requests and stranded capacity of the nodes, and the
+// unschedulable pods.
+func ConvertSimulationToCsv(simulations []controllers.PoolSimulation, units Units) [][]string {
+	result := [][]string{{"pool", units.CPUHeader("nodeCpu"), units.MemoryHeader("nodeMem"), "nodePods", "maxNodes", "currentNodes", "nodes",
+		units.CPUHeader("daemonSetCpu"), units.MemoryHeader("daemonSetMem"), "daemonSetPods",
+		units.CPUHeader("allocatableCpu"), units.MemoryHeader("allocatableMem"), "allocatablePods",
+		units.CPUHeader("requestCpu"), units.MemoryHeader("requestMem"), "requestPods",
+		units.CPUHeader("strandedCpu"), units.MemoryHeader("strandedMem"), "strandedPods", "unschedulablePods"}}
+	for _, simulation := range simulations {
+		pool := simulation.Pool
+		nodePods := pool.Pods
+		if nodePods == 0 {
+			nodePods = controllers.DefaultNodePods
+		}
+		record := []string{pool.Name, formatPoolQuantity(pool.CPU, units.FormatCPU), formatPoolQuantity(pool.Memory, units.FormatMemory),
+			strconv.FormatInt(nodePods, 10), strconv.Itoa(pool.MaxNodes), strconv.Itoa(pool.Nodes), strconv.Itoa(simulation.Nodes)}
+		for _, list := range []v1.ResourceList{simulation.DaemonSetOverhead, simulation.Allocatable, simulation.Requests, simulation.Stranded} {
+			pods := list[v1.ResourcePods]
+			record = append(record, units.FormatCPU(list[v1.ResourceCPU]), units.FormatMemory(list[v1.ResourceMemory]), pods.String())
+		}
+		result = append(result, append(record, strconv.Itoa(unschedulablePods(simulation))))
+	}
+	return result
+}
+
+// formatPoolQuantity formats value of a node pool by format, or returns it unchanged if it is not a quantity.
+func formatPoolQuantity(value string, format func(resource.Quantity) string) string {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return value
+	}
+	return format(quantity)
+}
+
+func unschedulablePods(simulation controllers.PoolSimulation) int {
+	pods := 0
+	for _, item := range simulation.Unschedulable {
+		pods += item.Pods
+	}
+	return pods
+}
+
+// ConvertUnschedulableToCsv returns the header and a record for every controller with pods which can not be placed
+// in a pool.
+func ConvertUnschedulableToCsv(simulations []controllers.PoolSimulation) [][]string {
+	result := [][]string{{"pool", "namespace", "controllerType", "controller", "pods", "reason"}}
+	for _, simulation := range simulations {
+		for _, item := range simulation.Unschedulable {
+			result = append(result, []string{simulation.Pool.Name, item.Namespace, item.ControllerType, item.Controller,
+				strconv.Itoa(item.Pods), item.Reason})
+		}
+	}
+	return result
+}
+
+// ConvertSimulationToTable returns the header and a record for every pool with the nodes needed, their requests with
+// the percentage of the allocatable, the stranded capacity and the number of unschedulable pods.
+func ConvertSimulationToTable(simulations []controllers.PoolSimulation, units Units) [][]string {
+	result := [][]string{{"POOL", "NODE", "NODES", "CURRENT", units.CPUHeader("CPU REQ"), units.MemoryHeader("MEM REQ"),
+		units.CPUHeader("STRANDED CPU"), units.MemoryHeader("STRANDED MEM"), "UNSCHEDULABLE"}}
+	for _, simulation := range simulations {
+		pool := simulation.Pool
+		ratios := map[v1.ResourceName]float64{}
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			if allocatable := simulation.Allocatable[name]; !allocatable.IsZero() {
+				requests := simulation.Requests[name]
+				ratios[name] = requests.AsApproximateFloat64() / allocatable.AsApproximateFloat64()
+			}
+		}
+		current := ""
+		if pool.Nodes > 0 {
+			current = strconv.Itoa(pool.Nodes)
+		}
+		result = append(result, []string{pool.Name, pool.CPU + "/" + pool.Memory, strconv.Itoa(simulation.Nodes), current,
+			formatAllocation(units.FormatCPU(simulation.Requests[v1.ResourceCPU]), ratios, v1.ResourceCPU),
+			formatAllocation(units.FormatMemory(simulation.Requests[v1.ResourceMemory]), ratios, v1.ResourceMemory),
+			units.FormatCPU(simulation.Stranded[v1.ResourceCPU]), units.FormatMemory(simulation.Stranded[v1.ResourceMemory]),
+			strconv.Itoa(unschedulablePods(simulation))})
+	}
+	return result
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"example.com/dev/k8s/controllers"
+	"io"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteTable writes records as a table aligned by tabwriter, the first record is the header.
+func WriteTable(w io.Writer, records [][]string) error {
+	tableWriter := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, record := range records {
+		if _, err := io.WriteString(tableWriter, strings.Join(record, "\t")+"\n"); err != nil {
+			return err
+		}
+	}
+	return tableWriter.Flush()
+}
+
+func generateTableHeaders(units Units, wide, usage, autoscaler, rollout bool) []string {
+	headers := []string{"NAMESPACE", "TYPE", "NAME", "REPLICAS",
+		units.CPUHeader("CPU REQ"), units.CPUHeader("CPU LIM"), units.MemoryHeader("MEM REQ"), units.MemoryHeader("MEM LIM")}
+	if autoscaler {
+		headers = append(headers, "MAX REPLICAS", units.CPUHeader("MAX CPU REQ"), units.MemoryHeader("MAX MEM REQ"))
+	}
+	if usage {
+		headers = append(headers, units.CPUHeader("CPU USE"), units.MemoryHeader("MEM USE"), "CPU USE/REQ", "MEM USE/REQ")
+	}
+	if wide {
+		headers = append(headers, "CONTAINERS", units.CPUHeader("POD CPU REQ"), units.CPUHeader("POD CPU LIM"),
+			units.MemoryHeader("POD MEM REQ"), units.MemoryHeader("POD MEM LIM"),
+			units.MemoryHeader("EPHEMERAL REQ"), units.MemoryHeader("EPHEMERAL LIM"),
+			units.MemoryHeader("EMPTYDIR"), units.MemoryHeader("STORAGE"), units.MemoryHeader("PV REQ"))
+		if rollout {
+			headers = append(headers, "STRATEGY", "PEAK REPLICAS", units.CPUHeader("PEAK CPU REQ"), units.MemoryHeader("PEAK MEM REQ"))
+		}
+	}
+	return headers
+}
+
+// tableRow holds the values of a table record which are summed in the footer.
+type tableRow struct {
+	replicas   int32
+	containers int
+	total      controllers.ResourceItem
+	// emptyDir and storage are multiplied by the replicas
+	emptyDir, storage, persistentStorage resource.Quantity
+	// usageCPU and usageMem are the usage of the pods with metrics, usageRequestCPU and usageRequestMem their requests
+	usageCPU, usageMem, usageRequestCPU, usageRequestMem resource.Quantity
+	// maxReplicas and maxTotal are at the max scale of the autoscaler, peakReplicas and peakTotal at the rollout peak
+	maxReplicas  int32
+	maxTotal     controllers.ResourceItem
+	peakReplicas int32
+	peakTotal    controllers.ResourceItem
+}
+
+func newTableRow(controllerItem controllers.ControllerItem) tableRow {
+	row := tableRow{
+		replicas:     controllerItem.Replicas,
+		containers:   len(controllerItem.InitContainer) + len(controllerItem.Container),
+		total:        controllerItem.Total,
+		emptyDir:     controllerItem.EmptyDir.DeepCopy(),
+		storage:      controllerItem.Storage.DeepCopy(),
+		maxReplicas:  controllerItem.MaxReplicas(),
+		maxTotal:     controllerItem.MaxTotal(),
+		peakReplicas: controllerItem.PeakReplicas(),
+		peakTotal:    controllerItem.PeakTotal(),
+	}
+	row.emptyDir.Mul(int64(controllerItem.Replicas))
+	row.storage.Mul(int64(controllerItem.Replicas))
+	for _, item := range controllerItem.PersistentStorage {
+		row.persistentStorage.Add(item.Request)
+	}
+	if usage := controllerItem.Usage; usage != nil {
+		row.usageCPU = usage.CPU.DeepCopy()
+		row.usageMem = usage.Memory.DeepCopy()
+		row.usageRequestCPU = controllerItem.EffectivePod.RequestCPU.DeepCopy()
+		row.usageRequestCPU.Mul(int64(usage.Pods))
+		row.usageRequestMem = controllerItem.EffectivePod.RequestMem.DeepCopy()
+		row.usageRequestMem.Mul(int64(usage.Pods))
+	}
+	return row
+}
+
+func (row *tableRow) add(newRow tableRow) {
+	row.replicas += newRow.replicas
+	row.containers += newRow.containers
+	row.total.RequestCPU.Add(newRow.total.RequestCPU)
+	row.total.LimitCPU.Add(newRow.total.LimitCPU)
+	row.total.RequestMem.Add(newRow.total.RequestMem)
+	row.total.LimitMem.Add(newRow.total.LimitMem)
+	row.total.RequestEphemeralStorate.Add(newRow.total.RequestEphemeralStorate)
+	row.total.LimitEphemeralStorate.Add(newRow.total.LimitEphemeralStorate)
+	row.emptyDir.Add(newRow.emptyDir)
+	row.storage.Add(newRow.storage)
+	row.persistentStorage.Add(newRow.persistentStorage)
+	row.usageCPU.Add(newRow.usageCPU)
+	row.usageMem.Add(newRow.usageMem)
+	row.usageRequestCPU.Add(newRow.usageRequestCPU)
+	row.usageRequestMem.Add(newRow.usageRequestMem)
+	row.maxReplicas += newRow.maxReplicas
+	row.maxTotal.RequestCPU.Add(newRow.maxTotal.RequestCPU)
+	row.maxTotal.RequestMem.Add(newRow.maxTotal.RequestMem)
+	row.peakReplicas += newRow.peakReplicas
+	row.peakTotal.RequestCPU.Add(newRow.peakTotal.RequestCPU)
+	row.peakTotal.RequestMem.Add(newRow.peakTotal.RequestMem)
+}
+
+func (row tableRow) peakValues(units Units) []string {
+	return []string{strconv.Itoa(int(row.peakReplicas)), units.FormatCPU(row.peakTotal.RequestCPU), units.FormatMemory(row.peakTotal.RequestMem)}
+}
+
+func (row tableRow) autoscalerValues(units Units) []string {
+	return []string{strconv.Itoa(int(row.maxReplicas)), units.FormatCPU(row.maxTotal.RequestCPU), units.FormatMemory(row.maxTotal.RequestMem)}
+}
+
+// formatPercent returns usage divided by request as a percentage, empty if there is no request.
+func formatPercent(usage, request int64) string {
+	if request == 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(math.Round(float64(usage)*100/float64(request))), 10) + "%"
+}
+
+func (row tableRow) usageValues(units Units) []string {
+	return []string{units.FormatCPU(row.usageCPU), units.FormatMemory(row.usageMem),
+		formatPercent(row.usageCPU.MilliValue(), row.usageRequestCPU.MilliValue()), formatPercent(row.usageMem.Value(), row.usageRequestMem.Value())}
+}
+
+func (row tableRow) values(units Units) []string {
+	return []string{strconv.Itoa(int(row.replicas)),
+		units.FormatCPU(row.total.RequestCPU), units.FormatCPU(row.total.LimitCPU),
+		units.FormatMemory(row.total.RequestMem), units.FormatMemory(row.total.LimitMem)}
+}
+
+func (row tableRow) wideValues(units Units, pod controllers.ResourceItem, showPod bool) []string {
+	podValues := []string{"", "", "", ""}
+	if showPod {
+		podValues = []string{units.FormatCPU(pod.RequestCPU), units.FormatCPU(pod.LimitCPU), units.FormatMemory(pod.RequestMem), units.FormatMemory(pod.LimitMem)}
+	}
+	result := append([]string{strconv.Itoa(row.containers)}, podValues...)
+	return append(result, units.FormatMemory(row.total.RequestEphemeralStorate), units.FormatMemory(row.total.LimitEphemeralStorate),
+		units.FormatMemory(row.emptyDir), units.FormatMemory(row.storage), units.FormatMemory(row.persistentStorage))
+}
+
+// ConvertResultToTable returns the header, a record with the totals of every controller and a footer with the totals
+// of all controllers, wide adds the pod level resources, the ephemeral storage and the volumes. The usage of the
+// metrics API and its percentage of the requests of the measured pods are added when any controller has usage, the
+// replicas and requests at the max scale of the autoscalers when any controller has an autoscaler. Wide adds the
+// update strategy and the replicas and requests at the rollout peak when any controller has an update strategy. The
+// source column leads when any controller was built from a kustomization.
+func ConvertResultToTable(content []controllers.ControllerItem, units Units, wide bool) [][]string {
+	usage, autoscaler, rollout, source := hasUsage(content), hasAutoscaler(content), hasRollout(content), hasSource(content)
+	headers := generateTableHeaders(units, wide, usage, autoscaler, rollout)
+	if source {
+		headers = append([]string{"SOURCE"}, headers...)
+	}
+	result := [][]string{headers}
+	var total tableRow
+	for _, controllerItem := range content {
+		row := newTableRow(controllerItem)
+		total.add(row)
+		record := append([]string{controllerItem.Namespace, controllerItem.ControllerType, controllerItem.Controller}, row.values(units)...)
+		if source {
+			record = append([]string{controllerItem.Source}, record...)
+		}
+		if autoscaler {
+			record = append(record, row.autoscalerValues(units)...)
+		}
+		if usage {
+			if controllerItem.Usage == nil {
+				record = append(record, "", "", "", "")
+			} else {
+				record = append(record, row.usageValues(units)...)
+			}
+		}
+		if wide {
+			record = append(record, row.wideValues(units, controllerItem.EffectivePod, true)...)
+			if rollout {
+				strategy := ""
+				if controllerItem.Rollout != nil {
+					strategy = controllerItem.Rollout.Strategy
+				}
+				record = append(append(record, strategy), row.peakValues(units)...)
+			}
+		}
+		result = append(result, record)
+	}
+	footer := []string{"TOTAL", "", ""}
+	if source {
+		footer = append(footer, "")
+	}
+	footer = append(footer, total.values(units)...)
+	if autoscaler {
+		footer = append(footer, total.autoscalerValues(units)...)
+	}
+	if usage {
+		footer = append(footer, total.usageValues(units)...)
+	}
+	if wide {
+		footer = append(footer, total.wideValues(units, controllers.ResourceItem{}, false)...)
+		if rollout {
+			footer = append(append(footer, ""), total.peakValues(units)...)
+		}
+	}
+	return append(result, footer)
+}
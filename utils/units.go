@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"strconv"
+	"strings"
+)
+
+const (
+	cpuCores      = "cores"
+	cpuMillicores = "millicores"
+)
+
+var memoryUnits = map[string]int64{
+	"bytes": 1,
+	"Ki":    1 << 10,
+	"Mi":    1 << 20,
+	"Gi":    1 << 30,
+	"Ti":    1 << 40,
+	"KB":    1e3,
+	"MB":    1e6,
+	"GB":    1e9,
+	"TB":    1e12,
+}
+
+// Units are the units cpu and memory/storage quantities are rendered in.
+type Units struct {
+	CPU    string
+	Memory string
+}
+
+// DefaultUnits keeps the historical output: millicores and Mi.
+var DefaultUnits = Units{CPU: cpuMillicores, Memory: "Mi"}
+
+// ParseUnits parses a comma separated list of a memory unit (bytes, Ki, Mi, Gi, Ti, KB, MB, GB, TB) and a cpu unit
+// (cores, millicores), e.g. "Gi,cores". The units which are not given keep their defaults.
+func ParseUnits(value string) (Units, error) {
+	units := DefaultUnits
+	for _, unit := range strings.Split(value, ",") {
+		unit = strings.TrimSpace(unit)
+		if unit == "" {
+			continue
+		}
+		if unit == cpuCores || unit == cpuMillicores {
+			units.CPU = unit
+		} else if _, ok := memoryUnits[unit]; ok {
+			units.Memory = unit
+		} else {
+			return units, fmt.Errorf("unknown unit %q", unit)
+		}
+	}
+	return units, nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func (units Units) FormatCPU(quantity resource.Quantity) string {
+	if units.CPU == cpuCores {
+		return formatFloat(float64(quantity.MilliValue()) / 1000)
+	}
+	return strconv.FormatInt(quantity.MilliValue(), 10)
+}
+
+func (units Units) FormatMemory(quantity resource.Quantity) string {
+	if units.Memory == "bytes" {
+		return strconv.FormatInt(quantity.Value(), 10)
+	}
+	return formatFloat(float64(quantity.Value()) / float64(memoryUnits[units.Memory]))
+}
+
+// CPUHeader returns name suffixed by the cpu unit, e.g. requestCpu(m).
+func (units Units) CPUHeader(name string) string {
+	if units.CPU == cpuCores {
+		return name
+	}
+	return name + "(m)"
+}
+
+// MemoryHeader returns name suffixed by the memory unit, e.g. requestMem(Mi).
+func (units Units) MemoryHeader(name string) string {
+	return name + "(" + units.Memory + ")"
+}
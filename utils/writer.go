@@ -6,9 +6,10 @@ import (
 	"example.com/dev/k8s/controllers"
 	"fmt"
 	"github.com/xuri/excelize/v2"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
+	"sigs.k8s.io/yaml"
 )
 
 const (
@@ -31,111 +32,152 @@ func checkAndCreateDirectory(filePath string, isFile bool) error {
 	return os.MkdirAll(fileDirectory, directoryPerm)
 }
 
-func WriteJsonFile(content interface{}, filePath string) error {
+// StdoutPath is the file path writing to stdout instead of a file.
+const StdoutPath = "-"
+
+// writeFile writes the content to filePath, or to stdout if filePath is StdoutPath.
+func writeFile(filePath string, write func(w io.Writer) error) error {
+	if filePath == StdoutPath {
+		return write(os.Stdout)
+	}
 	if err := checkAndCreateDirectory(filePath, true); err != nil {
 		return err
-	} else if contentJson, err := json.Marshal(content); err != nil {
+	}
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, filePerm)
+	if err != nil {
 		return err
-	} else {
-		return os.WriteFile(filePath, contentJson, filePerm)
 	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func WriteJsonFile(content interface{}, filePath string) error {
+	return writeFile(filePath, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(content)
+	})
+}
+
+func WriteYamlFile(content interface{}, filePath string) error {
+	return writeFile(filePath, func(w io.Writer) error {
+		if contentYaml, err := yaml.Marshal(content); err != nil {
+			return err
+		} else {
+			_, err = w.Write(contentYaml)
+			return err
+		}
+	})
 }
 
 func WriteCsvFile(content [][]string, header []string, filePath string) error {
-	if err := checkAndCreateDirectory(filePath, true); err != nil {
-		return err
-	} else if file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, filePerm); err != nil {
-		return err
-	} else {
-		defer file.Close()
-		csvWriter := csv.NewWriter(file)
-		defer csvWriter.Flush()
+	return writeFile(filePath, func(w io.Writer) error {
+		csvWriter := csv.NewWriter(w)
 		if len(header) > 0 {
 			csvWriter.Write(header)
 		}
-		csvWriter.WriteAll(content)
-	}
-	return nil
+		return csvWriter.WriteAll(content)
+	})
 }
 
-func generateControllerInfo(controllerItem controllers.ControllerItem) []string {
-	return []string{
-		controllerItem.Namespace, controllerItem.ControllerType, controllerItem.Controller, strconv.Itoa(int(controllerItem.Replicas)),
-		strconv.FormatInt(controllerItem.EmptyDir, 10), strconv.Itoa(controllerItem.Storage), strconv.FormatBool(controllerItem.StorageNoSize),
-	}
+// saveExcelFile saves excelFile to filePath, or writes it to stdout if filePath is StdoutPath.
+func saveExcelFile(excelFile *excelize.File, filePath string) error {
+	return writeFile(filePath, func(w io.Writer) error {
+		return excelFile.Write(w)
+	})
 }
 
-func generateContainerInfo(controllerItem controllers.ControllerItem) [][]string {
-	var result [][]string
-	containerType := "initContainer"
-	for _, container := range controllerItem.InitContainer {
-		result = append(result, []string{
-			containerType, container.Name, strconv.FormatInt(container.RequestCPU, 10), strconv.FormatInt(container.RequestMem, 10), strconv.FormatInt(container.RequestEphemeralStorate, 10),
-			strconv.FormatInt(container.LimitCPU, 10), strconv.FormatInt(container.LimitMem, 10), strconv.FormatInt(container.LimitEphemeralStorate, 10),
-		})
-
-	}
-	containerType = "container"
-	for _, container := range controllerItem.Container {
-		result = append(result, []string{
-			containerType, container.Name, strconv.FormatInt(container.RequestCPU, 10), strconv.FormatInt(container.RequestMem, 10), strconv.FormatInt(container.RequestEphemeralStorate, 10),
-			strconv.FormatInt(container.LimitCPU, 10), strconv.FormatInt(container.LimitMem, 10), strconv.FormatInt(container.LimitEphemeralStorate, 10),
-		})
+func writeExcelRow(excelFile *excelize.File, sheet string, rowIndex int, values []string) error {
+	for index, value := range values {
+		if cell, err := excelize.CoordinatesToCellName(index+1, rowIndex); err != nil {
+			return err
+		} else if err := excelFile.SetCellValue(sheet, cell, value); err != nil {
+			return err
+		}
 	}
-	return result
+	return nil
 }
 
-func WriteExcelFile(content []controllers.ControllerItem, filePath string, sheet string) error {
-	headers := []string{"namespace", "controllerType", "controller", "replicas", "emptyDir(m)", "storage(m)", "storageNoSize",
-		"containerType", "containerName", "requestCpu", "requestMem(m)", "requestEphemeralStorage(m)", "limitCpu", "limitMem(m)", "limitEphemeralStorage(m)"}
-	if err := checkAndCreateDirectory(filePath, true); err != nil {
-		return err
-	}
+func WriteExcelFile(report controllers.Report, filePath string, sheet string, units Units) error {
+	content := report.Responses
 	excelFile := excelize.NewFile()
 	defer excelFile.Close()
-	if index, err := excelFile.NewSheet(sheet); err != nil {
+	styles, err := newExcelStyles(excelFile)
+	if err != nil {
+		return err
+	}
+	if err := excelFile.SetSheetName(defaultSheet, summarySheet); err != nil {
+		return err
+	}
+	if err := writeExcelOverview(styles, content, units); err != nil {
+		return err
+	}
+	if _, err := excelFile.NewSheet(sheet); err != nil {
+		return err
+	}
+	usage, autoscaler, rollout, source := hasUsage(content), hasAutoscaler(content), hasRollout(content), hasSource(content)
+	headers := generateHeaders(units, usage, autoscaler, rollout)
+	if source {
+		headers = append([]string{"source"}, headers...)
+	}
+	if err := styles.setColumnStyles(sheet, headers, units); err != nil {
 		return err
-	} else {
-		excelFile.SetActiveSheet(index)
 	}
 	rowIndex := 1
 	//	writer header
-	for index, header := range headers {
-		if cell, err := excelize.CoordinatesToCellName(index+1, rowIndex); err != nil {
-			return err
-		} else if err := excelFile.SetCellValue(sheet, cell, header); err != nil {
-			return err
-		}
+	if err := styles.writeHeader(sheet, rowIndex, headers); err != nil {
+		return err
 	}
 	rowIndex++
 	for _, controllerItem := range content {
 		columnIndex := 1
-		records := len(controllerItem.Container) + len(controllerItem.InitContainer) - 1
-		for _, controllerInfo := range generateControllerInfo(controllerItem) {
+		containers := generateContainerInfo(controllerItem, units, usage)
+		// a controller without containers still takes a row
+		records := max(len(containers), 1)
+		controllerInfos := generateControllerInfo(controllerItem, units, usage, autoscaler, rollout)
+		if source {
+			controllerInfos = append([]string{controllerItem.Source}, controllerInfos...)
+		}
+		for _, controllerInfo := range controllerInfos {
 			if cell, err := excelize.CoordinatesToCellName(columnIndex, rowIndex); err != nil {
 				return err
-			} else if endCell, err := excelize.CoordinatesToCellName(columnIndex, rowIndex+records); err != nil {
+			} else if endCell, err := excelize.CoordinatesToCellName(columnIndex, rowIndex+records-1); err != nil {
 				return err
 			} else if err = excelFile.MergeCell(sheet, cell, endCell); err != nil {
 				return err
-			} else if err = excelFile.SetCellValue(sheet, cell, controllerInfo); err != nil {
+			} else if err = excelFile.SetCellValue(sheet, cell, excelCellValue(headers[columnIndex-1], controllerInfo)); err != nil {
 				return err
 			}
 			columnIndex++
 		}
-		for _, record := range generateContainerInfo(controllerItem) {
+		for recordIndex, record := range containers {
 			for recordColumn, column := range record {
-				if cell, err := excelize.CoordinatesToCellName(columnIndex+recordColumn, rowIndex); err != nil {
+				if cell, err := excelize.CoordinatesToCellName(columnIndex+recordColumn, rowIndex+recordIndex); err != nil {
 					return err
-				} else if err = excelFile.SetCellValue(sheet, cell, column); err != nil {
+				} else if err = excelFile.SetCellValue(sheet, cell, excelCellValue(headers[columnIndex+recordColumn-1], column)); err != nil {
 					return err
 				}
 			}
-			rowIndex++
 		}
+		rowIndex += records
 	}
-	if err := excelFile.SaveAs(filePath); err != nil {
+	if err := freezeHeader(excelFile, sheet, headers, rowIndex-1); err != nil {
 		return err
 	}
-	return nil
+	if err := highlightMissingResources(excelFile, sheet, headers, rowIndex-1); err != nil {
+		return err
+	}
+	if len(report.Summary) > 0 {
+		if err := writeExcelRecords(styles, groupsSheet, ConvertSummaryToCsv(report.Summary, units), units); err != nil {
+			return err
+		}
+	}
+	if len(report.Errors) > 0 {
+		if err := writeExcelRecords(styles, errorsSheet, ConvertErrorsToCsv(report.Errors), units); err != nil {
+			return err
+		}
+	}
+	excelFile.SetActiveSheet(0)
+	return saveExcelFile(excelFile, filePath)
 }
@@ -0,0 +1,602 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"example.com/dev/k8s/controllers"
+	"github.com/xuri/excelize/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func testResourceItem(cpu, memory string) controllers.ResourceItem {
+	return controllers.ResourceItem{
+		RequestCPU: resource.MustParse(cpu),
+		RequestMem: resource.MustParse(memory),
+		LimitCPU:   resource.MustParse(cpu),
+		LimitMem:   resource.MustParse(memory),
+	}
+}
+
+func testReport() controllers.Report {
+	return controllers.Report{
+		Responses: []controllers.ControllerItem{
+			{
+				Namespace:      "default",
+				ControllerType: "Deployment",
+				Controller:     "web",
+				Replicas:       2,
+				InitContainer:  []controllers.ContainerItem{{Name: "init", ResourceItem: testResourceItem("1", "64Mi")}},
+				Container: []controllers.ContainerItem{
+					{Name: "app", ResourceItem: testResourceItem("500m", "1536Ki")},
+					{Name: "sidecar", ResourceItem: testResourceItem("100m", "100Ki")},
+				},
+				EffectivePod: testResourceItem("1", "64Mi"),
+				Total:        testResourceItem("2", "128Mi"),
+				PersistentStorage: []controllers.StorageClassItem{
+					{StorageClass: "standard", Claims: 2, Request: resource.MustParse("2Gi")},
+					{StorageClass: "fast", Claims: 1, Request: resource.MustParse("1Gi"), Capacity: resource.MustParse("1Gi")},
+				},
+			},
+			{
+				Namespace:      "default",
+				ControllerType: "Pod",
+				Controller:     "bare",
+				Replicas:       1,
+				Container:      []controllers.ContainerItem{{Name: "app", ResourceItem: testResourceItem("250m", "1Gi")}},
+				EffectivePod:   testResourceItem("250m", "1Gi"),
+				Total:          testResourceItem("250m", "1Gi"),
+			},
+		},
+		Errors:  []controllers.CollectError{{Namespace: "kube-system", Kind: "StatefulSet", Message: "forbidden"}},
+		Summary: []controllers.GroupItem{{GroupBy: "namespace", Group: "default", Controllers: 2, Replicas: 3, Total: testResourceItem("2250m", "1152Mi")}},
+	}
+}
+
+func TestConvertResultToCsv(t *testing.T) {
+	content := testReport().Responses
+	result := ConvertResultToCsv(content, DefaultUnits)
+	if len(result) != 5 {
+		t.Fatalf("ConvertResultToCsv() returns %d records, want header and 4 containers", len(result))
+	}
+	headers := result[0]
+	for _, record := range result {
+		if len(record) != len(headers) {
+			t.Errorf("record %v has %d fields, want %d", record, len(record), len(headers))
+		}
+	}
+	column := func(name string) int {
+		for index, header := range headers {
+			if header == name {
+				return index
+			}
+		}
+		t.Fatalf("column %q not found in %v", name, headers)
+		return -1
+	}
+	tests := []struct {
+		record int
+		column string
+		want   string
+	}{
+		{1, "controller", "web"},
+		{1, "containerType", "initContainer"},
+		{1, "containerName", "init"},
+		{1, "requestCpu(m)", "1000"},
+		{2, "containerName", "app"},
+		{2, "requestMem(Mi)", "1.5"},
+		{3, "controller", "web"},
+		{3, "requestMem(Mi)", "0.09765625"},
+		{3, "totalRequestCpu(m)", "2000"},
+		{3, "persistentStorageRequest(Mi)", "3072"},
+		{3, "persistentStorageCapacity(Mi)", "1024"},
+		{3, "persistentStorageClasses", "standard:2048;fast:1024"},
+		{4, "controller", "bare"},
+		{4, "podRequestMem(Mi)", "1024"},
+	}
+	for _, test := range tests {
+		if got := result[test.record][column(test.column)]; got != test.want {
+			t.Errorf("record %d column %q = %q, want %q", test.record, test.column, got, test.want)
+		}
+	}
+
+	units, err := ParseUnits("Gi,cores")
+	if err != nil {
+		t.Fatalf("ParseUnits() error = %v", err)
+	}
+	result = ConvertResultToCsv(content, units)
+	if got := result[4][column("podRequestMem(Mi)")]; got != "1" {
+		t.Errorf("podRequestMem in Gi = %q, want %q", got, "1")
+	}
+	if got := result[4][column("podRequestCpu(m)")]; got != "0.25" {
+		t.Errorf("podRequestCpu in cores = %q, want %q", got, "0.25")
+	}
+}
+
+func TestWriteExcelFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "result", "resources.xlsx")
+	if err := WriteExcelFile(testReport(), filePath, "resources", DefaultUnits); err != nil {
+		t.Fatalf("WriteExcelFile() error = %v", err)
+	}
+	excelFile, err := excelize.OpenFile(filePath)
+	if err != nil {
+		t.Fatalf("open %s failed: %v", filePath, err)
+	}
+	defer excelFile.Close()
+
+	mergeCells, err := excelFile.GetMergeCells("resources")
+	if err != nil {
+		t.Fatalf("GetMergeCells() error = %v", err)
+	}
+	// every controller column of the deployment spans its 3 containers, the pod spans a single row
+	controllerColumns := len(generateControllerInfo(testReport().Responses[0], DefaultUnits, false, false, false))
+	var ranges []string
+	for _, mergeCell := range mergeCells {
+		ranges = append(ranges, mergeCell.GetStartAxis()+":"+mergeCell.GetEndAxis())
+	}
+	var want []string
+	for column := 1; column <= controllerColumns; column++ {
+		start, _ := excelize.CoordinatesToCellName(column, 2)
+		end, _ := excelize.CoordinatesToCellName(column, 4)
+		podCell, _ := excelize.CoordinatesToCellName(column, 5)
+		want = append(want, start+":"+end, podCell+":"+podCell)
+	}
+	sort.Strings(ranges)
+	sort.Strings(want)
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("merge cells = %v, want %v", ranges, want)
+	}
+
+	rows, err := excelFile.GetRows("resources")
+	if err != nil {
+		t.Fatalf("GetRows() error = %v", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("sheet has %d rows, want header and 4 containers", len(rows))
+	}
+	if rows[1][2] != "web" || rows[4][2] != "bare" {
+		t.Errorf("controller cells = %q, %q, want %q, %q", rows[1][2], rows[4][2], "web", "bare")
+	}
+	if rows[3][controllerColumns+1] != "sidecar" {
+		t.Errorf("container cell = %q, want %q", rows[3][controllerColumns+1], "sidecar")
+	}
+
+	if got, want := excelFile.GetSheetList(), []string{"summary", "resources", "groups", "errors"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sheets = %v, want %v", got, want)
+	}
+	groupRows, err := excelFile.GetRows("groups")
+	if err != nil {
+		t.Fatalf("GetRows() error = %v", err)
+	}
+	if len(groupRows) != 2 || groupRows[1][1] != "default" || groupRows[1][4] != "2250" {
+		t.Errorf("groups sheet = %v", groupRows)
+	}
+	summaryRows, err := excelFile.GetRows("summary")
+	if err != nil {
+		t.Fatalf("GetRows() error = %v", err)
+	}
+	// totals by namespace, by type and the chart data of cpu and memory by namespace
+	if len(summaryRows) != 12 || summaryRows[1][1] != "default" || summaryRows[4][1] != "Deployment" || summaryRows[8][2] != "250" {
+		t.Errorf("summary sheet = %v", summaryRows)
+	}
+
+	// numbers are written as numbers with the unit in the number format
+	// O2 is podLimitCpu(m) of the deployment
+	if value, err := excelFile.GetCellValue("resources", "O2", excelize.Options{RawCellValue: true}); err != nil || value != "1000" {
+		t.Errorf("value of O2 = %q, %v, want %q", value, err, "1000")
+	}
+	if cellType, err := excelFile.GetCellType("resources", "O2"); err != nil || cellType == excelize.CellTypeSharedString || cellType == excelize.CellTypeInlineString {
+		t.Errorf("cell type of O2 = %v, %v, want a number", cellType, err)
+	}
+	if styleID, err := excelFile.GetCellStyle("resources", "O2"); err != nil {
+		t.Errorf("GetCellStyle() error = %v", err)
+	} else if style, err := excelFile.GetStyle(styleID); err != nil || style.CustomNumFmt == nil || *style.CustomNumFmt != `General" m"` {
+		t.Errorf("style of O2 = %+v, %v, want the millicores number format", style, err)
+	}
+	if panes, err := excelFile.GetPanes("resources"); err != nil || !panes.Freeze || panes.YSplit != 1 {
+		t.Errorf("panes = %+v, %v, want a frozen header", panes, err)
+	}
+	conditionalFormats, err := excelFile.GetConditionalFormats("resources")
+	if err != nil || len(conditionalFormats) != 1 {
+		t.Errorf("conditional formats = %v, %v, want the missing resources highlight", conditionalFormats, err)
+	}
+
+	errorRows, err := excelFile.GetRows("errors")
+	if err != nil {
+		t.Fatalf("GetRows() error = %v", err)
+	}
+	if len(errorRows) != 2 || errorRows[1][0] != "kube-system" {
+		t.Errorf("errors sheet = %v", errorRows)
+	}
+}
+
+func TestReadJsonReport(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "report.json")
+	if err := WriteJsonFile(testReport(), filePath); err != nil {
+		t.Fatalf("WriteJsonFile() error = %v", err)
+	}
+	report, err := ReadJsonReport(filePath)
+	if err != nil {
+		t.Fatalf("ReadJsonReport() error = %v", err)
+	}
+	if len(report.Responses) != 2 || len(report.Errors) != 1 || report.Responses[0].Total.RequestCPU.Cmp(resource.MustParse("2")) != 0 {
+		t.Errorf("ReadJsonReport() = %+v", report)
+	}
+
+	// the legacy reports store millicores and MiB
+	filePath = filepath.Join(dir, "legacy.json")
+	legacy := `{"responses":[{"namespace":"default","controllerType":"Deployment","controller":"web","replicas":2,"emptyDir":64,` +
+		`"initContainer":[{"name":"init","requestCpu":1000,"requestMem":64,"limitCpu":1000,"limitMem":64}],` +
+		`"container":[{"name":"app","requestCpu":500,"requestMem":128,"limitCpu":0,"limitMem":256}]}]}`
+	if err := os.WriteFile(filePath, []byte(legacy), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	report, err = ReadJsonReport(filePath)
+	if err != nil || len(report.Responses) != 1 {
+		t.Fatalf("ReadJsonReport() = %+v, %v", report, err)
+	}
+	web := report.Responses[0]
+	app := web.Container[0]
+	if app.RequestCPU.Cmp(resource.MustParse("500m")) != 0 || app.RequestMem.Cmp(resource.MustParse("128Mi")) != 0 || !app.LimitCPU.IsZero() ||
+		web.EmptyDir.Cmp(resource.MustParse("64Mi")) != 0 {
+		t.Errorf("legacy container = %+v, emptyDir = %v, want 500m and 128Mi", app, web.EmptyDir)
+	}
+	// the init container sets the cpu of the pod and the app container its memory
+	if web.Total.RequestCPU.Cmp(resource.MustParse("2")) != 0 || web.Total.RequestMem.Cmp(resource.MustParse("256Mi")) != 0 {
+		t.Errorf("legacy total = %+v, want 2 and 256Mi", web.Total)
+	}
+}
+
+func TestConvertResultToTable(t *testing.T) {
+	content := testReport().Responses
+	result := ConvertResultToTable(content, DefaultUnits, false)
+	want := [][]string{
+		{"NAMESPACE", "TYPE", "NAME", "REPLICAS", "CPU REQ(m)", "CPU LIM(m)", "MEM REQ(Mi)", "MEM LIM(Mi)"},
+		{"default", "Deployment", "web", "2", "2000", "2000", "128", "128"},
+		{"default", "Pod", "bare", "1", "250", "250", "1024", "1024"},
+		{"TOTAL", "", "", "3", "2250", "2250", "1152", "1152"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("ConvertResultToTable() = %v, want %v", result, want)
+	}
+
+	result = ConvertResultToTable(content, DefaultUnits, true)
+	for _, record := range result {
+		if len(record) != len(result[0]) {
+			t.Errorf("record %v has %d fields, want %d", record, len(record), len(result[0]))
+		}
+	}
+	footer := result[len(result)-1]
+	// CONTAINERS and PV REQ(Mi) of the footer
+	if footer[8] != "4" || footer[len(footer)-1] != "3072" {
+		t.Errorf("wide footer = %v", footer)
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteTable(&buffer, want[:2]); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 || strings.Index(lines[0], "TYPE") != strings.Index(lines[1], "Deployment") {
+		t.Errorf("WriteTable() = %q, want aligned columns", buffer.String())
+	}
+}
+
+func TestWriteYamlFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "report.yaml")
+	if err := WriteYamlFile(testReport(), filePath); err != nil {
+		t.Fatalf("WriteYamlFile() error = %v", err)
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "controller: web") || !strings.Contains(string(content), "requestCpu: 500m") {
+		t.Errorf("WriteYamlFile() = %s", content)
+	}
+}
+
+func TestTemplatePrinter(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{
+			output: `go-template={{range .responses}}{{.controller}} {{cpu .total.requestCpu}} {{memory .total.requestMem}}{{"\n"}}{{end}}`,
+			want:   "web 2000 128\nbare 250 1024\n",
+		},
+		{
+			output: `jsonpath={range .responses[*]}{.controller}={.replicas}{"\n"}{end}`,
+			want:   "web=2\nbare=1\n",
+		},
+		{
+			output: "custom-columns=NAME:.controller,CONTAINERS:.container[*].name,INIT:.initContainer[0].name",
+			want:   "NAME  CONTAINERS   INIT\nweb   app,sidecar  init\nbare  app          <none>\n",
+		},
+	}
+	for _, test := range tests {
+		printer, err := NewTemplatePrinter(test.output, DefaultUnits)
+		if err != nil || printer == nil {
+			t.Fatalf("NewTemplatePrinter(%q) = %v, %v", test.output, printer, err)
+		}
+		var buffer bytes.Buffer
+		if err := printer.Print(&buffer, testReport()); err != nil {
+			t.Fatalf("Print(%q) error = %v", test.output, err)
+		}
+		if buffer.String() != test.want {
+			t.Errorf("Print(%q) = %q, want %q", test.output, buffer.String(), test.want)
+		}
+	}
+
+	if printer, err := NewTemplatePrinter("wide", DefaultUnits); printer != nil || err != nil {
+		t.Errorf("NewTemplatePrinter(wide) = %v, %v, want no printer", printer, err)
+	}
+	for _, output := range []string{"go-template={{.responses", "jsonpath={.responses[", "custom-columns=", "custom-columns=NAME"} {
+		if _, err := NewTemplatePrinter(output, DefaultUnits); err == nil {
+			t.Errorf("NewTemplatePrinter(%q) returns no error", output)
+		}
+	}
+}
+
+func TestUsageColumns(t *testing.T) {
+	content := testReport().Responses
+	if result := ConvertResultToCsv(content, DefaultUnits); len(result[0]) != len(generateHeaders(DefaultUnits, false, false, false)) {
+		t.Fatalf("ConvertResultToCsv() without usage has headers %v", result[0])
+	}
+	ratio := 0.3333333
+	content[0].Usage = &controllers.UsageItem{CPU: resource.MustParse("1"), Memory: resource.MustParse("64Mi"), Pods: 2, CPURequestRatio: &ratio}
+	content[0].Container[0].Usage = &controllers.UsageItem{CPU: resource.MustParse("250m"), Memory: resource.MustParse("1Mi"), Pods: 2, CPURequestRatio: &ratio}
+
+	result := ConvertResultToCsv(content, DefaultUnits)
+	headers := result[0]
+	for _, record := range result {
+		if len(record) != len(headers) {
+			t.Fatalf("record %v has %d fields, want %d", record, len(record), len(headers))
+		}
+	}
+	values := map[string]string{}
+	// the record of the app container
+	for index, header := range headers {
+		values[header] = result[2][index]
+	}
+	want := map[string]string{"usagePods": "2", "totalUsageCpu(m)": "1000", "usageCpu(m)": "250", "cpuRequestRatio": "0.333", "cpuLimitRatio": ""}
+	for header, value := range want {
+		if values[header] != value {
+			t.Errorf("%s = %q, want %q", header, values[header], value)
+		}
+	}
+	if DefaultUnits.excelNumberFormat("cpuRequestRatio") != "0.0%" {
+		t.Errorf("excelNumberFormat(cpuRequestRatio) = %q", DefaultUnits.excelNumberFormat("cpuRequestRatio"))
+	}
+
+	table := ConvertResultToTable(content, DefaultUnits, false)
+	wantTable := [][]string{
+		{"NAMESPACE", "TYPE", "NAME", "REPLICAS", "CPU REQ(m)", "CPU LIM(m)", "MEM REQ(Mi)", "MEM LIM(Mi)", "CPU USE(m)", "MEM USE(Mi)", "CPU USE/REQ", "MEM USE/REQ"},
+		// 1 of 2 pods * 1 cpu, 64Mi of 2 pods * 64Mi
+		{"default", "Deployment", "web", "2", "2000", "2000", "128", "128", "1000", "64", "50%", "50%"},
+		{"default", "Pod", "bare", "1", "250", "250", "1024", "1024", "", "", "", ""},
+		{"TOTAL", "", "", "3", "2250", "2250", "1152", "1152", "1000", "64", "50%", "50%"},
+	}
+	if !reflect.DeepEqual(table, wantTable) {
+		t.Errorf("ConvertResultToTable() = %v, want %v", table, wantTable)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := WriteExcelFile(controllers.Report{Responses: content}, filePath, "resources", DefaultUnits); err != nil {
+		t.Fatalf("WriteExcelFile() error = %v", err)
+	}
+}
+
+func TestConvertRecommendationsToTable(t *testing.T) {
+	recommendations := []controllers.Recommendation{{
+		Namespace: "default", ControllerType: "Deployment", Controller: "web", ContainerType: "container", Container: "app", Policy: "default",
+		Current:     testResourceItem("500m", "128Mi"),
+		Recommended: controllers.ResourceItem{RequestCPU: resource.MustParse("220m"), RequestMem: resource.MustParse("128Mi"), LimitMem: resource.MustParse("256Mi")},
+		History: controllers.UsageHistoryItem{Window: "7d",
+			CPU:    controllers.QuantileItem{P50: resource.MustParse("100m"), P95: resource.MustParse("200m"), Max: resource.MustParse("1")},
+			Memory: controllers.QuantileItem{P50: resource.MustParse("64Mi"), P95: resource.MustParse("100Mi"), Max: resource.MustParse("200Mi")}},
+	}}
+	want := []string{"default", "Deployment", "web", "app", "default", "500->220", "500->0", "128", "128->256", "200", "1000", "100", "200"}
+	if result := ConvertRecommendationsToTable(recommendations, DefaultUnits); len(result) != 2 || !reflect.DeepEqual(result[1], want) {
+		t.Errorf("ConvertRecommendationsToTable() = %v, want %v", result, want)
+	}
+	if result := ConvertRecommendationsToCsv(recommendations, DefaultUnits); len(result) != 2 || len(result[0]) != len(result[1]) {
+		t.Errorf("ConvertRecommendationsToCsv() = %v", result)
+	}
+}
+
+func testNodeReport() controllers.NodeReport {
+	node := controllers.NodeItem{
+		Name:          "worker-1",
+		Capacity:      v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("8Gi"), v1.ResourcePods: resource.MustParse("110"), "nvidia.com/gpu": resource.MustParse("2")},
+		Allocatable:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("8Gi"), v1.ResourcePods: resource.MustParse("110"), "nvidia.com/gpu": resource.MustParse("2")},
+		Requests:      v1.ResourceList{v1.ResourceCPU: resource.MustParse("1500m"), v1.ResourceMemory: resource.MustParse("2Gi"), v1.ResourcePods: resource.MustParse("3"), "nvidia.com/gpu": resource.MustParse("1")},
+		Limits:        v1.ResourceList{v1.ResourceCPU: resource.MustParse("6"), v1.ResourceMemory: resource.MustParse("4Gi"), "nvidia.com/gpu": resource.MustParse("1")},
+		RequestRatios: map[v1.ResourceName]float64{v1.ResourceCPU: 0.375, v1.ResourceMemory: 0.25, v1.ResourcePods: 3.0 / 110, "nvidia.com/gpu": 0.5},
+		LimitRatios:   map[v1.ResourceName]float64{v1.ResourceCPU: 1.5, v1.ResourceMemory: 0.5, v1.ResourcePods: 0, "nvidia.com/gpu": 0.5},
+	}
+	total := node
+	total.Name = "TOTAL"
+	return controllers.NodeReport{
+		Nodes:               []controllers.NodeItem{node},
+		Total:               total,
+		UnscheduledRequests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourcePods: resource.MustParse("1")},
+	}
+}
+
+func TestConvertNodesToTable(t *testing.T) {
+	result := ConvertNodesToTable(testNodeReport(), DefaultUnits, true)
+	if len(result) != 4 || result[2][0] != unscheduledNode || result[3][0] != "TOTAL" {
+		t.Fatalf("ConvertNodesToTable() = %v, want the node, the unscheduled pods and the total", result)
+	}
+	want := []string{"worker-1", "4000", "1500 (38%)", "6000 (150%)", "8192", "2048 (25%)", "4096 (50%)", "3/110", "false", "0", "0", "1/2"}
+	if !reflect.DeepEqual(result[1], want) {
+		t.Errorf("node record = %v, want %v", result[1], want)
+	}
+	if header := result[0][len(result[0])-1]; header != "NVIDIA.COM/GPU REQ/ALLOC" {
+		t.Errorf("last header = %s, want the extended resource", header)
+	}
+}
+
+func TestConvertNodesToCsv(t *testing.T) {
+	result := ConvertNodesToCsv(testNodeReport(), DefaultUnits)
+	// pods have no limit ratio
+	if len(result) != 4 || len(result[0]) != len(result[1]) || len(result[0]) != 2+5*6-1 {
+		t.Fatalf("ConvertNodesToCsv() = %v, want a header and the records of 5 resources", result)
+	}
+	if result[2][0] != unscheduledNode || result[3][0] != "TOTAL" || len(result[2]) != len(result[0]) || len(result[3]) != len(result[0]) {
+		t.Errorf("ConvertNodesToCsv() = %v, want the unscheduled pods and the total after the node", result)
+	}
+	for _, header := range result[0] {
+		if header == "podsLimitRatio" {
+			t.Errorf("header %s found in %v", header, result[0])
+		}
+	}
+	for index, header := range []string{"cpuLimits(m)", "cpuRequestRatio", "memAllocatable(Mi)", "nvidia.com/gpuRequests"} {
+		found := false
+		for _, value := range result[0] {
+			found = found || value == header
+		}
+		if !found {
+			t.Errorf("header %d %s not found in %v", index, header, result[0])
+		}
+	}
+
+	filePath := filepath.Join(t.TempDir(), "nodes.xlsx")
+	if err := WriteNodesExcelFile(testNodeReport(), filePath, DefaultUnits); err != nil {
+		t.Fatalf("WriteNodesExcelFile() error = %v", err)
+	}
+	excelFile, err := excelize.OpenFile(filePath)
+	if err != nil {
+		t.Fatalf("open %s failed: %v", filePath, err)
+	}
+	defer excelFile.Close()
+	rows, err := excelFile.GetRows(nodesSheet)
+	if err != nil || len(rows) != 4 || rows[1][0] != "worker-1" || rows[3][0] != "TOTAL" {
+		t.Errorf("GetRows() = %v, %v, want the header, worker-1, the unscheduled pods and the total", rows, err)
+	}
+}
+
+func TestConvertSimulationToTable(t *testing.T) {
+	simulations := []controllers.PoolSimulation{{
+		Pool:              controllers.NodePool{Name: "small", CPU: "4", Memory: "8Gi"},
+		Nodes:             2,
+		DaemonSetOverhead: v1.ResourceList{v1.ResourceCPU: resource.MustParse("200m"), v1.ResourceMemory: resource.MustParse("256Mi"), v1.ResourcePods: resource.MustParse("1")},
+		Allocatable:       v1.ResourceList{v1.ResourceCPU: resource.MustParse("8"), v1.ResourceMemory: resource.MustParse("16Gi"), v1.ResourcePods: resource.MustParse("220")},
+		Requests:          v1.ResourceList{v1.ResourceCPU: resource.MustParse("6"), v1.ResourceMemory: resource.MustParse("4Gi"), v1.ResourcePods: resource.MustParse("7")},
+		Stranded:          v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("12Gi"), v1.ResourcePods: resource.MustParse("213")},
+		Unschedulable:     []controllers.UnschedulableItem{{Namespace: "default", ControllerType: "Deployment", Controller: "huge", Pods: 2, Reason: controllers.ReasonLargerThanNode}},
+	}}
+	want := []string{"small", "4/8Gi", "2", "", "6000 (75%)", "4096 (25%)", "2000", "12288", "2"}
+	if result := ConvertSimulationToTable(simulations, DefaultUnits); len(result) != 2 || !reflect.DeepEqual(result[1], want) {
+		t.Errorf("ConvertSimulationToTable() = %v, want %v", result, want)
+	}
+	result := ConvertSimulationToCsv(simulations, DefaultUnits)
+	if len(result) != 2 || len(result[0]) != len(result[1]) || result[1][1] != "4000" || result[1][3] != "110" {
+		t.Errorf("ConvertSimulationToCsv() = %v", result)
+	}
+	if result := ConvertUnschedulableToCsv(simulations); len(result) != 2 || result[1][3] != "huge" || result[1][4] != "2" {
+		t.Errorf("ConvertUnschedulableToCsv() = %v", result)
+	}
+}
+
+func TestAutoscalerColumns(t *testing.T) {
+	content := testReport().Responses
+	content[0].Autoscaler = &controllers.AutoscalerItem{Name: "web", MinReplicas: 2, MaxReplicas: 5, MaxTotal: testResourceItem("5", "320Mi")}
+	result := ConvertResultToTable(content, DefaultUnits, false)
+	want := [][]string{
+		{"NAMESPACE", "TYPE", "NAME", "REPLICAS", "CPU REQ(m)", "CPU LIM(m)", "MEM REQ(Mi)", "MEM LIM(Mi)", "MAX REPLICAS", "MAX CPU REQ(m)", "MAX MEM REQ(Mi)"},
+		{"default", "Deployment", "web", "2", "2000", "2000", "128", "128", "5", "5000", "320"},
+		{"default", "Pod", "bare", "1", "250", "250", "1024", "1024", "1", "250", "1024"},
+		{"TOTAL", "", "", "3", "2250", "2250", "1152", "1152", "6", "5250", "1344"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("ConvertResultToTable() = %v, want %v", result, want)
+	}
+
+	records := ConvertResultToCsv(content, DefaultUnits)
+	if len(records[0]) != len(generateHeaders(DefaultUnits, false, true, false)) || len(records[0]) != len(records[1]) {
+		t.Fatalf("ConvertResultToCsv() header = %v, record = %v", records[0], records[1])
+	}
+	index := -1
+	for column, header := range records[0] {
+		if header == "minReplicas" {
+			index = column
+		}
+	}
+	// the pod without autoscaler is at its current replicas
+	if index < 0 || records[1][index-1] != "web" || records[1][index] != "2" || records[len(records)-1][index+1] != "1" {
+		t.Errorf("autoscaler columns = %v, %v", records[1], records[len(records)-1])
+	}
+
+	groups := []controllers.GroupItem{{GroupBy: "namespace", Group: "default", Controllers: 2, Replicas: 3, Autoscalers: 1, MaxReplicas: 6, MaxTotal: testResourceItem("5250m", "1344Mi")}}
+	summary := ConvertSummaryToCsv(groups, DefaultUnits)
+	if header := summary[0][len(summary[0])-6]; header != "maxTotalRequestCpu(m)" || summary[1][len(summary[1])-6] != "5250" {
+		t.Errorf("ConvertSummaryToCsv() = %v", summary)
+	}
+	if summary := ConvertSummaryToCsv(testReport().Summary, DefaultUnits); len(summary[0]) != 13 {
+		t.Errorf("ConvertSummaryToCsv() without autoscaler has %d columns, want 13", len(summary[0]))
+	}
+}
+
+func TestRolloutColumns(t *testing.T) {
+	content := testReport().Responses
+	content[0].Rollout = &controllers.RolloutItem{Strategy: "RollingUpdate", Surge: 1, PeakTotal: testResourceItem("3", "192Mi")}
+	result := ConvertResultToTable(content, DefaultUnits, true)
+	header, web, bare, total := result[0], result[1], result[2], result[len(result)-1]
+	want := []string{"STRATEGY", "PEAK REPLICAS", "PEAK CPU REQ(m)", "PEAK MEM REQ(Mi)"}
+	if !reflect.DeepEqual(header[len(header)-4:], want) {
+		t.Fatalf("ConvertResultToTable() header = %v, want it to end with %v", header, want)
+	}
+	// the pod without update strategy is at its current replicas
+	if !reflect.DeepEqual(web[len(web)-4:], []string{"RollingUpdate", "3", "3000", "192"}) || !reflect.DeepEqual(bare[len(bare)-4:], []string{"", "1", "250", "1024"}) ||
+		!reflect.DeepEqual(total[len(total)-4:], []string{"", "4", "3250", "1216"}) {
+		t.Errorf("rollout columns = %v, %v, %v", web, bare, total)
+	}
+	if narrow := ConvertResultToTable(content, DefaultUnits, false); len(narrow[0]) != 8 {
+		t.Errorf("ConvertResultToTable() without wide = %v, want no rollout columns", narrow[0])
+	}
+
+	records := ConvertResultToCsv(content, DefaultUnits)
+	if len(records[0]) != len(generateHeaders(DefaultUnits, false, false, true)) || len(records[0]) != len(records[1]) {
+		t.Fatalf("ConvertResultToCsv() header = %v, record = %v", records[0], records[1])
+	}
+	index := -1
+	for column, header := range records[0] {
+		if header == "surge" {
+			index = column
+		}
+	}
+	if index < 0 || records[1][index-1] != "RollingUpdate" || records[1][index] != "1" || records[1][index+1] != "3" || records[len(records)-1][index+1] != "1" {
+		t.Errorf("rollout columns = %v, %v", records[1], records[len(records)-1])
+	}
+
+	groups := []controllers.GroupItem{{GroupBy: "namespace", Group: "default", Controllers: 2, Replicas: 3, PeakReplicas: 4, PeakTotal: testResourceItem("3250m", "1216Mi")}}
+	summary := ConvertSummaryToCsv(groups, DefaultUnits)
+	if header := summary[0][len(summary[0])-6]; header != "peakTotalRequestCpu(m)" || summary[1][len(summary[1])-6] != "3250" || summary[1][len(summary[1])-7] != "4" {
+		t.Errorf("ConvertSummaryToCsv() = %v", summary)
+	}
+}
+
+func TestSourceColumn(t *testing.T) {
+	content := testReport().Responses
+	content[0].Source = "overlays/prod"
+	result := ConvertResultToTable(content, DefaultUnits, false)
+	if result[0][0] != "SOURCE" || result[1][0] != "overlays/prod" || result[2][0] != "" || result[len(result)-1][0] != "TOTAL" || result[len(result)-1][4] != "3" {
+		t.Errorf("ConvertResultToTable() = %v, want the source column first", result)
+	}
+	records := ConvertResultToCsv(content, DefaultUnits)
+	if records[0][0] != "source" || records[1][0] != "overlays/prod" || records[1][1] != "default" || len(records[0]) != len(records[1]) {
+		t.Errorf("ConvertResultToCsv() = %v, want the source column first", records[:2])
+	}
+	if result := ConvertResultToTable(testReport().Responses, DefaultUnits, false); result[0][0] != "NAMESPACE" {
+		t.Errorf("ConvertResultToTable() without source = %v", result[0])
+	}
+}